@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/livechat"
+	log "github.com/sirupsen/logrus"
+)
+
+// AdminRouter returns router (protected with basic auth) which allows
+// to inspect installed licenses and release chats from bots.
+func AdminRouter(cfg *adminConfig, manager bot.BotManager) http.Handler {
+	router := chi.NewRouter()
+	router.Use(middleware.BasicAuth("admin", map[string]string{cfg.Username: cfg.Password}))
+
+	router.Get("/licenses", func(w http.ResponseWriter, r *http.Request) {
+		sendJSON(w, http.StatusOK, manager.Licenses())
+	})
+
+	router.Route("/licenses/{licenseID}", func(router chi.Router) {
+		router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			id, err := licenseIDParam(r)
+			if err != nil {
+				sendAdminError(w, err)
+				return
+			}
+
+			license, err := manager.License(id)
+			if err != nil {
+				sendAdminError(w, err)
+				return
+			}
+			sendJSON(w, http.StatusOK, license)
+		})
+
+		// Uninstall removes license from memory and store even if
+		// LiveChat API refuses to clean it up, such partial failure
+		// is reported with 502.
+		router.Delete("/", func(w http.ResponseWriter, r *http.Request) {
+			id, err := licenseIDParam(r)
+			if err != nil {
+				sendAdminError(w, err)
+				return
+			}
+			if _, err := manager.License(id); err != nil {
+				sendAdminError(w, err)
+				return
+			}
+
+			if err := manager.UninstallApp(r.Context(), id); err != nil {
+				log.WithError(err).WithField("license_id", id).Warn("License has been uninstalled without cleanup in LiveChat")
+				sendJSON(w, http.StatusBadGateway, map[string]string{
+					"message": fmt.Sprintf("license has been removed, but its cleanup in LiveChat failed: %s", err),
+				})
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		router.Delete("/chats/{chatID}", func(w http.ResponseWriter, r *http.Request) {
+			id, err := licenseIDParam(r)
+			if err != nil {
+				sendAdminError(w, err)
+				return
+			}
+
+			chatID := livechat.ChatID(chi.URLParam(r, "chatID"))
+			if err := manager.ReleaseChat(r.Context(), id, chatID); err != nil {
+				sendAdminError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+
+	return router
+}
+
+var errInvalidLicenseID = errors.New("admin: invalid license id")
+
+func licenseIDParam(r *http.Request) (livechat.LicenseID, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "licenseID"))
+	if err != nil || id <= 0 {
+		return 0, errInvalidLicenseID
+	}
+	return livechat.LicenseID(id), nil
+}
+
+func sendAdminError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errInvalidLicenseID):
+		status = http.StatusBadRequest
+	case errors.Is(err, bot.ErrLicenseNotFound), errors.Is(err, bot.ErrChatNotFound):
+		status = http.StatusNotFound
+	default:
+		log.WithError(err).Error("Admin request failed")
+	}
+
+	sendJSON(w, status, map[string]string{"message": err.Error()})
+}
+
+func sendJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
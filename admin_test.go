@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+type adminManager struct {
+	bot.BotManager
+	uninstalled  []livechat.LicenseID
+	uninstallErr error
+	released     []livechat.ChatID
+}
+
+func (m *adminManager) Licenses() []*bot.LicenseInfo {
+	license, _ := m.License(1234)
+	return []*bot.LicenseInfo{license}
+}
+
+func (m *adminManager) License(id livechat.LicenseID) (*bot.LicenseInfo, error) {
+	if id != 1234 {
+		return nil, fmt.Errorf("%w (license id: %v)", bot.ErrLicenseNotFound, id)
+	}
+	return &bot.LicenseInfo{ID: id, Bots: []*bot.BotInfo{{ID: "bot_1", Chats: []livechat.ChatID{"chat_1"}}}}, nil
+}
+
+func (m *adminManager) UninstallApp(ctx context.Context, id livechat.LicenseID) error {
+	m.uninstalled = append(m.uninstalled, id)
+	return m.uninstallErr
+}
+
+func (m *adminManager) ReleaseChat(ctx context.Context, id livechat.LicenseID, chatID livechat.ChatID) error {
+	if chatID != "chat_1" {
+		return bot.ErrChatNotFound
+	}
+	m.released = append(m.released, chatID)
+	return nil
+}
+
+func Test_AdminRouter(t *testing.T) {
+	manager := &adminManager{}
+	handler := AdminRouter(&adminConfig{Username: "admin", Password: "secret"}, manager)
+
+	tests := map[string]struct {
+		method string
+		path   string
+		status int
+	}{
+		"list licenses":        {http.MethodGet, "/licenses", http.StatusOK},
+		"get license":          {http.MethodGet, "/licenses/1234", http.StatusOK},
+		"get unknown license":  {http.MethodGet, "/licenses/4321", http.StatusNotFound},
+		"get invalid license":  {http.MethodGet, "/licenses/abcd", http.StatusBadRequest},
+		"uninstall license":    {http.MethodDelete, "/licenses/1234", http.StatusNoContent},
+		"uninstall unknown":    {http.MethodDelete, "/licenses/4321", http.StatusNotFound},
+		"release chat":         {http.MethodDelete, "/licenses/1234/chats/chat_1", http.StatusNoContent},
+		"release unknown chat": {http.MethodDelete, "/licenses/1234/chats/chat_2", http.StatusNotFound},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.SetBasicAuth("admin", "secret")
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	assert.Equal(t, []livechat.LicenseID{1234}, manager.uninstalled)
+	assert.Equal(t, []livechat.ChatID{"chat_1"}, manager.released)
+}
+
+func Test_AdminRouter_UninstallPartially(t *testing.T) {
+	manager := &adminManager{uninstallErr: errors.New("cannot unregister webhook")}
+	handler := AdminRouter(&adminConfig{Username: "admin", Password: "secret"}, manager)
+
+	req := httptest.NewRequest(http.MethodDelete, "/licenses/1234", nil)
+	req.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, map[string]string{
+		"message": "license has been removed, but its cleanup in LiveChat failed: cannot unregister webhook",
+	}, body)
+	assert.Equal(t, []livechat.LicenseID{1234}, manager.uninstalled)
+}
+
+func Test_AdminRouter_ListLicenses(t *testing.T) {
+	handler := AdminRouter(&adminConfig{Username: "admin", Password: "secret"}, &adminManager{})
+
+	req := httptest.NewRequest(http.MethodGet, "/licenses", nil)
+	req.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var licenses []*bot.LicenseInfo
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&licenses))
+	if assert.Len(t, licenses, 1) {
+		assert.Equal(t, []livechat.ChatID{"chat_1"}, licenses[0].Bots[0].Chats)
+	}
+}
+
+func Test_AdminRouter_Unauthorized(t *testing.T) {
+	handler := AdminRouter(&adminConfig{Username: "admin", Password: "secret"}, &adminManager{})
+
+	req := httptest.NewRequest(http.MethodGet, "/licenses", nil)
+	req.SetBasicAuth("admin", "invalid")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+var (
+	ErrLicenseNotFound = errors.New("bot: license is not installed")
+	ErrChatNotFound    = errors.New("bot: chat is not assigned to any bot")
+)
+
+// Admin allows to inspect and manage state of installed apps.
+type Admin interface {
+	Licenses() []*LicenseInfo
+	License(livechat.LicenseID) (*LicenseInfo, error)
+	// Authorized returns licenses which have obtained OAuth token.
+	Authorized() []livechat.LicenseID
+	// ReleaseChat removes bot from the chat and forgets the conversation.
+	ReleaseChat(context.Context, livechat.LicenseID, livechat.ChatID) error
+}
+
+type LicenseInfo struct {
+	ID livechat.LicenseID `json:"license_id"`
+	// Webhooks maps action to the ID of registered webhook (empty for RTM).
+	Webhooks map[string]string `json:"webhooks,omitempty"`
+	Bots     []*BotInfo        `json:"bots"`
+}
+
+type BotInfo struct {
+	ID    livechat.AgentID  `json:"id"`
+	Chats []livechat.ChatID `json:"chats"`
+}
@@ -0,0 +1,163 @@
+package agents
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+// Agent is a bot serving chats. Chat is served by one agent of the
+// collection at a time, registering it moves it from the previous one.
+// Agent belongs to the collection it has been registered in last.
+type Agent struct {
+	ID livechat.AgentID
+	// MaxChats is a number of chats agent serves at once, unlimited if 0.
+	MaxChats int
+
+	// mu guards fields below. Collection the agent is registered in
+	// is always locked before, since it indexes chats of agent.
+	mu    sync.Mutex
+	owner *collection
+	seq   uint64
+	// chats maps served chats to the order they have been registered in.
+	chats map[livechat.ChatID]uint64
+	// removed are chats which have been taken over by human.
+	removed map[livechat.ChatID]struct{}
+}
+
+func (a *Agent) RegisterChat(chatIDs ...livechat.ChatID) error {
+	unlock := a.lock()
+	defer unlock()
+
+	if a.chats == nil {
+		a.chats = make(map[livechat.ChatID]uint64, len(chatIDs))
+	}
+
+	for _, chatID := range chatIDs {
+		delete(a.removed, chatID)
+		if _, ok := a.chats[chatID]; ok {
+			continue
+		}
+
+		a.seq++
+		a.chats[chatID] = a.seq
+		if a.owner != nil {
+			a.owner.index(a, chatID)
+		}
+	}
+	return nil
+}
+
+func (a *Agent) UnregisterChat(chatID livechat.ChatID) error {
+	unlock := a.lock()
+	defer unlock()
+
+	if _, ok := a.chats[chatID]; !ok {
+		return nil
+	}
+
+	a.drop(chatID)
+	if a.removed == nil {
+		a.removed = make(map[livechat.ChatID]struct{})
+	}
+	a.removed[chatID] = struct{}{}
+	return nil
+}
+
+// ForgetChat drops the chat from both assigned and unregistered chats,
+// it reports whether agent knew the chat.
+func (a *Agent) ForgetChat(chatID livechat.ChatID) bool {
+	unlock := a.lock()
+	defer unlock()
+
+	return a.forget(chatID)
+}
+
+func (a *Agent) forget(chatID livechat.ChatID) bool {
+	_, assigned := a.chats[chatID]
+	_, removed := a.removed[chatID]
+
+	a.drop(chatID)
+	delete(a.removed, chatID)
+	return assigned || removed
+}
+
+// drop removes chat from served chats and from index of owner.
+func (a *Agent) drop(chatID livechat.ChatID) {
+	if _, ok := a.chats[chatID]; !ok {
+		return
+	}
+
+	delete(a.chats, chatID)
+	if a.owner != nil {
+		a.owner.unindex(a, chatID)
+	}
+}
+
+// lock locks agent together with the collection it is registered in.
+func (a *Agent) lock() func() {
+	for {
+		a.mu.Lock()
+		owner := a.owner
+		if owner == nil {
+			return a.mu.Unlock
+		}
+		a.mu.Unlock()
+
+		owner.mu.Lock()
+		a.mu.Lock()
+		if a.owner == owner {
+			return func() {
+				a.mu.Unlock()
+				owner.mu.Unlock()
+			}
+		}
+
+		// Agent has been moved to another collection in the meantime.
+		a.mu.Unlock()
+		owner.mu.Unlock()
+	}
+}
+
+// Load returns number of chats agent serves.
+func (a *Agent) Load() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.chats)
+}
+
+// load returns number of served chats and whether chat is one of them.
+func (a *Agent) load(chatID livechat.ChatID) (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, ok := a.chats[chatID]
+	return len(a.chats), ok
+}
+
+// Chats returns chats assigned to agent in order they have been registered.
+func (a *Agent) Chats() []livechat.ChatID {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.sortedChats()
+}
+
+// Snapshot returns copy of agent's state.
+func (a *Agent) Snapshot() AgentSnapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return AgentSnapshot{ID: a.ID, MaxChats: a.MaxChats, Chats: a.sortedChats()}
+}
+
+func (a *Agent) sortedChats() []livechat.ChatID {
+	chats := make([]livechat.ChatID, 0, len(a.chats))
+	for chatID := range a.chats {
+		chats = append(chats, chatID)
+	}
+	sort.Slice(chats, func(i, j int) bool { return a.chats[chats[i]] < a.chats[chats[j]] })
+	return chats
+}
@@ -0,0 +1,39 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Agent_RegisterChat(t *testing.T) {
+	agent := NewAgent("abcd_1")
+
+	agent.RegisterChat("chat_1", "chat_2", "chat_3")
+	assert.Len(t, agent.chats, 3)
+
+	agent.RegisterChat("chat_1", "chat_4", "chat_5")
+	assert.Len(t, agent.chats, 5)
+	assert.Equal(t, []livechat.ChatID{"chat_1", "chat_2", "chat_3", "chat_4", "chat_5"}, agent.Chats())
+
+	agent.UnregisterChat("chat_2")
+	assert.Len(t, agent.chats, 4)
+	assert.Len(t, agent.removed, 1)
+
+	agent.RegisterChat("chat_2")
+	assert.Empty(t, agent.removed)
+	assert.Equal(t, []livechat.ChatID{"chat_1", "chat_3", "chat_4", "chat_5", "chat_2"}, agent.Chats())
+}
+
+func Test_Agent_ForgetChat(t *testing.T) {
+	agent := NewAgent("abcd_1")
+	agent.RegisterChat("chat_1", "chat_2")
+	agent.UnregisterChat("chat_2")
+
+	assert.True(t, agent.ForgetChat("chat_1"))
+	assert.True(t, agent.ForgetChat("chat_2"))
+	assert.False(t, agent.ForgetChat("chat_3"))
+	assert.Empty(t, agent.chats)
+	assert.Empty(t, agent.removed)
+}
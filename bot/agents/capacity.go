@@ -0,0 +1,75 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/livechat/onboarding/livechat/web"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrAtCapacity is returned when every bot which may take the chat
+// already serves as many chats as it can.
+var ErrAtCapacity = errors.New("bot: every bot is at capacity")
+
+// Capacity limits load of bots of a single license. Bots take any
+// number of chats and are never added if capacity is nil.
+type Capacity struct {
+	// MaxChats is a number of chats served by bot at once. Limit of
+	// bot's account (max_chats_count) is used if empty.
+	MaxChats int
+	// MinBots is a number of bots enabled on install, 1 if empty.
+	MinBots int
+	// MaxBots limits bots added when every one of them is at capacity,
+	// bots are not added if it is not greater than the number of bots.
+	MaxBots int
+}
+
+// ChatsLimit returns MaxChats of bot whose account is limited to
+// accountLimit chats (0 means no limit).
+func (c *Capacity) ChatsLimit(accountLimit int) int {
+	if c == nil || c.MaxChats == 0 {
+		return accountLimit
+	}
+	return c.MaxChats
+}
+
+func (c *Capacity) minBots() int {
+	if c == nil || c.MinBots == 0 {
+		return 1
+	}
+	return c.MinBots
+}
+
+// Grow creates, enables and registers one more bot if limit of bots
+// has not been reached yet, ErrAtCapacity is returned otherwise.
+func Grow(ctx context.Context, lcHTTP web.LivechatRequests, bots Agents, capacity *Capacity) (*Agent, error) {
+	if capacity == nil || bots.Len() >= capacity.MaxBots {
+		return nil, ErrAtCapacity
+	}
+
+	agent, err := addBot(ctx, lcHTTP, bots, capacity)
+	if err != nil {
+		return nil, fmt.Errorf("bot_factory: %w", err)
+	}
+
+	log.WithField("agent_id", agent.ID).WithField("agents_num", bots.Len()).Info("Added bot, the others are at capacity")
+	return agent, nil
+}
+
+func addBot(ctx context.Context, lcHTTP web.LivechatRequests, bots Agents, capacity *Capacity) (*Agent, error) {
+	agent, err := createBot(ctx, lcHTTP, capacity)
+	if err != nil {
+		return nil, err
+	}
+	if err := enableBot(ctx, lcHTTP, agent.ID); err != nil {
+		go removeBot(ctx, lcHTTP, agent.ID)
+		return nil, err
+	}
+	if err := bots.Register(agent); err != nil {
+		return nil, err
+	}
+
+	return agent, nil
+}
@@ -0,0 +1,189 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+// collection keeps agents in order of registration and indexes them
+// by ID and by chats they serve.
+type collection struct {
+	mu     sync.RWMutex
+	agents []*Agent
+	byID   map[livechat.AgentID]*Agent
+	byChat map[livechat.ChatID]*Agent
+}
+
+func (a *collection) Register(bot *Agent) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.byID[bot.ID]; ok {
+		return fmt.Errorf("bot: agent (id: %v) is already registered", bot.ID)
+	}
+	if a.byID == nil {
+		a.byID = make(map[livechat.AgentID]*Agent)
+	}
+
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+
+	bot.owner = a
+	for chatID := range bot.chats {
+		a.index(bot, chatID)
+	}
+
+	a.agents = append(a.agents, bot)
+	a.byID[bot.ID] = bot
+	return nil
+}
+
+func (a *collection) Unregister(agentID livechat.AgentID) (*Agent, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	unregisteredAgent, ok := a.byID[agentID]
+	if !ok {
+		return nil, errors.New("bot: agent cannot be found")
+	}
+
+	unregisteredAgent.mu.Lock()
+	defer unregisteredAgent.mu.Unlock()
+
+	for chatID := range unregisteredAgent.chats {
+		a.unindex(unregisteredAgent, chatID)
+	}
+	unregisteredAgent.owner = nil
+
+	newAgents := make([]*Agent, 0, len(a.agents)-1)
+	for _, agent := range a.agents {
+		if agent != unregisteredAgent {
+			newAgents = append(newAgents, agent)
+		}
+	}
+
+	a.agents = newAgents
+	delete(a.byID, agentID)
+	return unregisteredAgent, nil
+}
+
+// index assigns the chat to the agent, chat is dropped by the agent which
+// served it so far. Both collection and agent have to be locked.
+func (a *collection) index(agent *Agent, chatID livechat.ChatID) {
+	if a.byChat == nil {
+		a.byChat = make(map[livechat.ChatID]*Agent)
+	}
+
+	if previous, ok := a.byChat[chatID]; ok && previous != agent {
+		previous.mu.Lock()
+		delete(previous.chats, chatID)
+		previous.mu.Unlock()
+	}
+	a.byChat[chatID] = agent
+}
+
+// unindex removes the chat of agent from index. Both collection
+// and agent have to be locked.
+func (a *collection) unindex(agent *Agent, chatID livechat.ChatID) {
+	if a.byChat[chatID] == agent {
+		delete(a.byChat, chatID)
+	}
+}
+
+func (a *collection) Len() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return len(a.agents)
+}
+
+// Snapshot copies agents while collection is locked, so chats cannot
+// move between agents in the meantime.
+func (a *collection) Snapshot() []AgentSnapshot {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	snapshot := make([]AgentSnapshot, 0, len(a.agents))
+	for _, agent := range a.agents {
+		snapshot = append(snapshot, agent.Snapshot())
+	}
+	return snapshot
+}
+
+func (a *collection) FindByChat(chatID livechat.ChatID) (*Agent, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if agent, ok := a.byChat[chatID]; ok {
+		return agent, nil
+	}
+
+	return nil, fmt.Errorf("bot: agent cannot be found")
+}
+
+func (a *collection) ForgetChat(chatID livechat.ChatID) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	known := false
+	for _, agent := range a.agents {
+		agent.mu.Lock()
+		if agent.forget(chatID) {
+			known = true
+		}
+		agent.mu.Unlock()
+	}
+	return known
+}
+
+func (a *collection) FindByChatExclude(chatID livechat.ChatID) (*Agent, error) {
+	return a.FindFree(chatID, nil)
+}
+
+func (a *collection) FindFree(chatID livechat.ChatID, accept func(index int) bool) (*Agent, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var free *Agent
+	freeLoad, atCapacity := 0, false
+
+	for i, agent := range a.agents {
+		if accept != nil && !accept(i) {
+			continue
+		}
+
+		load, hasChat := agent.load(chatID)
+		if hasChat {
+			continue
+		}
+		if agent.MaxChats > 0 && load >= agent.MaxChats {
+			atCapacity = true
+			continue
+		}
+		if free == nil || load < freeLoad {
+			free, freeLoad = agent, load
+		}
+	}
+
+	if free != nil {
+		return free, nil
+	}
+	if atCapacity {
+		return nil, ErrAtCapacity
+	}
+	return nil, fmt.Errorf("bot: agent cannot be found")
+}
+
+func (a *collection) FindByID(agentID livechat.AgentID) (*Agent, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if agent, ok := a.byID[agentID]; ok {
+		return agent, nil
+	}
+
+	return nil, fmt.Errorf("bot: agent cannot be found")
+}
@@ -0,0 +1,290 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Agents(t *testing.T) {
+	t.Run("register agent", func(t *testing.T) {
+		agentsCollection := &collection{}
+		agentsCollection.Register(&Agent{ID: "abcd"})
+
+		assert.Len(t, agentsCollection.agents, 1)
+	})
+
+	t.Run("unregister agent", func(t *testing.T) {
+		agentsCollection := &collection{}
+		agentsCollection.Register(&Agent{ID: "abcd"})
+
+		agent, err := agentsCollection.Unregister("abcd")
+		assert.NoError(t, err)
+		assert.Len(t, agentsCollection.agents, 0)
+		assert.Equal(t, livechat.AgentID("abcd"), agent.ID)
+	})
+
+	t.Run("unregister agent (not found)", func(t *testing.T) {
+		agentsCollection := &collection{}
+
+		_, err := agentsCollection.Unregister("abcd")
+		assert.Len(t, agentsCollection.agents, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("find by chat exclude (success)", func(t *testing.T) {
+		agentsCollection := &collection{}
+		agentsCollection.Register(&Agent{ID: "abcd"})
+
+		agent, err := agentsCollection.FindByChatExclude(livechat.ChatID("abcd"))
+		assert.NoError(t, err)
+		assert.Equal(t, livechat.AgentID("abcd"), agent.ID)
+	})
+
+	t.Run("find by chat exclude (fail)", func(t *testing.T) {
+		agentsCollection := &collection{}
+
+		_, err := agentsCollection.FindByChatExclude(livechat.ChatID("abcd"))
+		assert.Error(t, err)
+	})
+
+	t.Run("find free (accepted agents only)", func(t *testing.T) {
+		agentsCollection := &collection{}
+		agentsCollection.Register(&Agent{ID: "abcd"})
+		agentsCollection.Register(&Agent{ID: "efgh"})
+
+		agent, err := agentsCollection.FindFree(livechat.ChatID("abcd"), func(index int) bool { return index == 1 })
+		assert.NoError(t, err)
+		assert.Equal(t, livechat.AgentID("efgh"), agent.ID)
+
+		_, err = agentsCollection.FindFree(livechat.ChatID("abcd"), func(int) bool { return false })
+		assert.Error(t, err)
+	})
+
+	t.Run("find free (least loaded)", func(t *testing.T) {
+		agentsCollection := &collection{}
+		agentsCollection.Register(helperCreateAgent(t, "abcd", "chat_1", "chat_2"))
+		agentsCollection.Register(helperCreateAgent(t, "efgh", "chat_3"))
+		agentsCollection.Register(helperCreateAgent(t, "ijkl", "chat_4"))
+
+		agent, err := agentsCollection.FindFree(livechat.ChatID("chat_5"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, livechat.AgentID("efgh"), agent.ID)
+	})
+
+	t.Run("find free (at capacity)", func(t *testing.T) {
+		agentsCollection := &collection{}
+		first, second := helperCreateAgent(t, "abcd", "chat_1"), helperCreateAgent(t, "efgh", "chat_2")
+		first.MaxChats, second.MaxChats = 1, 2
+		agentsCollection.Register(first)
+		agentsCollection.Register(second)
+
+		agent, err := agentsCollection.FindFree(livechat.ChatID("chat_3"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, livechat.AgentID("efgh"), agent.ID)
+		agent.RegisterChat("chat_3")
+
+		_, err = agentsCollection.FindFree(livechat.ChatID("chat_4"), nil)
+		assert.True(t, errors.Is(err, ErrAtCapacity))
+	})
+
+	t.Run("find by chat (success)", func(t *testing.T) {
+		agentsCollection := &collection{}
+		agentsCollection.Register(helperCreateAgent(t, "abcd", "abcd"))
+
+		agent, err := agentsCollection.FindByChat(livechat.ChatID("abcd"))
+		assert.NoError(t, err)
+		assert.Equal(t, livechat.AgentID("abcd"), agent.ID)
+	})
+
+	t.Run("find by chat (fail)", func(t *testing.T) {
+		agentsCollection := &collection{}
+		agentsCollection.Register(&Agent{ID: "abcd"})
+
+		_, err := agentsCollection.FindByChat(livechat.ChatID("abcd"))
+		assert.Error(t, err)
+	})
+
+	t.Run("forget chat", func(t *testing.T) {
+		agentsCollection := &collection{}
+		taken := helperCreateAgent(t, "efgh", "abcd")
+		taken.UnregisterChat("abcd")
+		agentsCollection.Register(helperCreateAgent(t, "abcd", "abcd"))
+		agentsCollection.Register(taken)
+
+		assert.True(t, agentsCollection.ForgetChat(livechat.ChatID("abcd")))
+		assert.False(t, agentsCollection.ForgetChat(livechat.ChatID("abcd")))
+
+		_, err := agentsCollection.FindByChat(livechat.ChatID("abcd"))
+		assert.Error(t, err)
+	})
+
+	t.Run("index follows chats of agents", func(t *testing.T) {
+		agentsCollection := NewCollection()
+		first, second := NewAgent("abcd"), NewAgent("efgh")
+		agentsCollection.Register(first)
+		agentsCollection.Register(second)
+
+		first.RegisterChat("chat_1", "chat_2")
+		agent, err := agentsCollection.FindByChat("chat_1")
+		assert.NoError(t, err)
+		assert.Equal(t, first, agent)
+
+		second.RegisterChat("chat_1")
+		agent, err = agentsCollection.FindByChat("chat_1")
+		assert.NoError(t, err)
+		assert.Equal(t, second, agent)
+		assert.Equal(t, []livechat.ChatID{"chat_2"}, first.Chats())
+
+		second.UnregisterChat("chat_1")
+		_, err = agentsCollection.FindByChat("chat_1")
+		assert.Error(t, err)
+
+		agentsCollection.Unregister("abcd")
+		_, err = agentsCollection.FindByChat("chat_2")
+		assert.Error(t, err)
+		first.RegisterChat("chat_3")
+		_, err = agentsCollection.FindByChat("chat_3")
+		assert.Error(t, err)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		agentsCollection := NewCollection()
+		agent := helperCreateAgent(t, "abcd", "chat_1")
+		agentsCollection.Register(agent)
+
+		snapshot := agentsCollection.Snapshot()
+		agentsCollection.Register(NewAgent("efgh"))
+		agent.RegisterChat("chat_2")
+
+		assert.Equal(t, []AgentSnapshot{{ID: "abcd", Chats: []livechat.ChatID{"chat_1"}}}, snapshot)
+		assert.Len(t, agentsCollection.Snapshot(), 2)
+	})
+}
+
+func helperCreateAgent(t *testing.T, id livechat.AgentID, chats ...livechat.ChatID) *Agent {
+	t.Helper()
+
+	agent := NewAgent(id)
+	agent.RegisterChat(chats...)
+	return agent
+}
+
+func Test_Agents_Concurrent(t *testing.T) {
+	agentsCollection := NewCollection()
+	for i := 0; i < 4; i++ {
+		agentsCollection.Register(NewAgent(livechat.AgentID(fmt.Sprintf("agent_%d", i))))
+	}
+
+	// Extra agents come and go after the permanent ones, so chats are
+	// given to the permanent ones.
+	permanent := func(index int) bool { return index < 4 }
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			for j := 0; j < 200; j++ {
+				chatID := livechat.ChatID(fmt.Sprintf("chat_%d_%d", worker, j))
+				agent, err := agentsCollection.FindFree(chatID, permanent)
+				if !assert.NoError(t, err) {
+					return
+				}
+				agent.RegisterChat(chatID)
+
+				found, err := agentsCollection.FindByChat(chatID)
+				assert.NoError(t, err)
+				assert.Equal(t, agent, found)
+
+				switch j % 3 {
+				case 0:
+					agent.UnregisterChat(chatID)
+				case 1:
+					agentsCollection.ForgetChat(chatID)
+				}
+				agentsCollection.Snapshot()
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 200; j++ {
+			agentID := livechat.AgentID(fmt.Sprintf("extra_%d", j))
+			agentsCollection.Register(NewAgent(agentID))
+			agentsCollection.Unregister(agentID)
+		}
+	}()
+	wg.Wait()
+
+	served := 0
+	for _, agent := range agentsCollection.Snapshot() {
+		served += len(agent.Chats)
+	}
+	assert.Equal(t, 8*66, served)
+	assert.Equal(t, 4, agentsCollection.Len())
+}
+
+func Benchmark_Agents_Register(b *testing.B) {
+	agentsCollection := NewCollection()
+	var next int64
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			id := atomic.AddInt64(&next, 1)
+			agentsCollection.Register(NewAgent(livechat.AgentID(strconv.FormatInt(id, 10))))
+		}
+	})
+}
+
+func Benchmark_Agents_FindByChat(b *testing.B) {
+	agentsCollection := helperCreateLoadedCollection(b, 10, 1000)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			agentsCollection.FindByChat(livechat.ChatID(fmt.Sprintf("chat_%d_%d", i%10, i%1000)))
+			i++
+		}
+	})
+}
+
+func Benchmark_Agents_Unregister(b *testing.B) {
+	agentsCollection := helperCreateLoadedCollection(b, 10, 1000)
+	var next int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			id := livechat.AgentID(strconv.FormatInt(atomic.AddInt64(&next, 1), 10))
+			agent := NewAgent(id)
+			agent.RegisterChat(livechat.ChatID(id))
+			agentsCollection.Register(agent)
+			agentsCollection.Unregister(id)
+		}
+	})
+}
+
+func helperCreateLoadedCollection(b *testing.B, agentsNum, chatsNum int) Agents {
+	b.Helper()
+
+	agentsCollection := NewCollection()
+	for i := 0; i < agentsNum; i++ {
+		agent := NewAgent(livechat.AgentID(fmt.Sprintf("agent_%d", i)))
+		for j := 0; j < chatsNum; j++ {
+			agent.RegisterChat(livechat.ChatID(fmt.Sprintf("chat_%d_%d", i, j)))
+		}
+		agentsCollection.Register(agent)
+	}
+	return agentsCollection
+}
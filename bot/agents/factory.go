@@ -11,24 +11,24 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func Initialize(ctx context.Context, lcHTTP web.LivechatRequests) (Agents, error) {
+// Initialize creates a bot and enables every bot of the license. More
+// bots are created if there are fewer of them than capacity requires.
+func Initialize(ctx context.Context, lcHTTP web.LivechatRequests, capacity *Capacity) (Agents, error) {
 	agents := NewCollection()
-	_, err := createBot(ctx, lcHTTP)
+	_, err := createBot(ctx, lcHTTP, capacity)
 	if err != nil {
 		return agents, fmt.Errorf("bot_factory: %w", err)
 	}
 
-	bots, err := fetchBots(ctx, lcHTTP)
+	bots, err := fetchBots(ctx, lcHTTP, capacity)
 	if err != nil {
 		return agents, fmt.Errorf("bot_factory: %w", err)
 	}
-	if bots.Len() == 0 {
+	if len(bots) == 0 {
 		return agents, errors.New("bot_factory: received empty list of bots")
 	}
 
-	insideBots, unlockBots := bots.Get()
-	defer unlockBots()
-	for _, insideBot := range insideBots {
+	for _, insideBot := range bots {
 		if err := enableBot(ctx, lcHTTP, insideBot.ID); err != nil {
 			go removeBot(ctx, lcHTTP, insideBot.ID)
 			continue
@@ -40,52 +40,82 @@ func Initialize(ctx context.Context, lcHTTP web.LivechatRequests) (Agents, error
 		return agents, fmt.Errorf("bot_factory: received empty list of bots")
 	}
 
+	for agents.Len() < capacity.minBots() {
+		if _, err := addBot(ctx, lcHTTP, agents, capacity); err != nil {
+			return agents, fmt.Errorf("bot_factory: %w", err)
+		}
+	}
+
 	log.WithField("agents_num", agents.Len()).Debug("Registered agents")
 	return agents, nil
 }
 
+// Terminate sets routing status of every agent to "offline". Agents are
+// disabled in parallel, collection is not locked while they are. Errors
+// of every agent which cannot be disabled are returned together.
 func Terminate(ctx context.Context, lcHTTP web.LivechatRequests, bots Agents) error {
-	agentsInside, unlock := bots.Get()
-	defer unlock()
+	snapshot := bots.Snapshot()
+	errs := make([]error, len(snapshot))
 
 	wg := sync.WaitGroup{}
-	wg.Add(bots.Len())
+	wg.Add(len(snapshot))
 
-	for _, agent := range agentsInside {
-		go func(bot *Agent) {
+	for i, agent := range snapshot {
+		go func(i int, botID livechat.AgentID) {
 			defer wg.Done()
-			disableBot(ctx, lcHTTP, bot.ID)
-		}(agent)
+			if err := disableBot(ctx, lcHTTP, botID); err != nil {
+				errs[i] = fmt.Errorf("bot_factory: %w (agent id: %v)", err, botID)
+			}
+		}(i, agent.ID)
 	}
 
 	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Enable sets routing status of every agent to "accepting_chats".
+func Enable(ctx context.Context, lcHTTP web.LivechatRequests, bots Agents) error {
+	for _, agent := range bots.Snapshot() {
+		if err := enableBot(ctx, lcHTTP, agent.ID); err != nil {
+			return fmt.Errorf("bot_factory: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func createBot(ctx context.Context, lcHTTP web.LivechatRequests) (*Agent, error) {
-	response, err := lcHTTP.CreateBot(ctx, &livechat.CreateBotRequest{Name: "OnboardingGG (bot created by app)"})
+func createBot(ctx context.Context, lcHTTP web.LivechatRequests, capacity *Capacity) (*Agent, error) {
+	maxChats := capacity.ChatsLimit(0)
+	response, err := lcHTTP.CreateBot(ctx, &livechat.CreateBotRequest{
+		Name:          "OnboardingGG (bot created by app)",
+		MaxChatsCount: maxChats,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("create_bot: %w", err)
 	}
 
-	return NewAgent(response.ID), nil
+	agent := NewAgent(response.ID)
+	agent.MaxChats = maxChats
+	return agent, nil
 }
 
-func fetchBots(ctx context.Context, lcHTTP web.LivechatRequests) (Agents, error) {
-	collection := NewCollection()
-	botsResponse, err := lcHTTP.ListBots(ctx, &livechat.ListBotsRequest{All: true})
+func fetchBots(ctx context.Context, lcHTTP web.LivechatRequests, capacity *Capacity) ([]*Agent, error) {
+	botsResponse, err := lcHTTP.ListBots(ctx, &livechat.ListBotsRequest{All: true, Fields: []string{"max_chats_count"}})
 	if err != nil {
-		return collection, fmt.Errorf("fetch_bots: %w", err)
+		return nil, fmt.Errorf("fetch_bots: %w", err)
 	}
 	if len(botsResponse) == 0 {
-		return collection, fmt.Errorf("fetch_bots: empty list")
+		return nil, fmt.Errorf("fetch_bots: empty list")
 	}
 
+	bots := make([]*Agent, 0, len(botsResponse))
 	for _, botID := range botsResponse {
-		collection.Register(NewAgent(botID.ID))
+		agent := NewAgent(botID.ID)
+		agent.MaxChats = capacity.ChatsLimit(botID.MaxChatsCount)
+		bots = append(bots, agent)
 	}
 
-	return collection, nil
+	return bots, nil
 }
 
 func removeBot(ctx context.Context, lcHTTP web.LivechatRequests, botID livechat.AgentID) error {
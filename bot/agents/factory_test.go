@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web"
 	"github.com/livechat/onboarding/livechat/web/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -35,11 +36,54 @@ func Test_Initialize(t *testing.T) {
 		Once().
 		Return(&livechat.DeleteBotResponse{}, nil)
 
-	agents, err := Initialize(ctx, lcHTTP)
+	agents, err := Initialize(ctx, lcHTTP, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, agents.Len())
 }
 
+func Test_Initialize_MinBots(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("CreateBot", ctx, mock.MatchedBy(func(p *livechat.CreateBotRequest) bool {
+		return p.MaxChatsCount == 5
+	})).Once().Return(&livechat.CreateBotResponse{ID: "abcd_1"}, nil)
+	lcHTTP.On("CreateBot", ctx, mock.Anything).Once().Return(&livechat.CreateBotResponse{ID: "abcd_2"}, nil)
+	lcHTTP.On("ListBots", ctx, mock.Anything).Once().Return([]*livechat.ListBotResponse{{ID: "abcd_1"}}, nil)
+	lcHTTP.On("SetRoutingStatus", ctx, mock.Anything).Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	agents, err := Initialize(ctx, lcHTTP, &Capacity{MaxChats: 5, MinBots: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, agents.Len())
+
+	added, err := agents.FindByID("abcd_2")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, added.MaxChats)
+}
+
+func Test_Grow(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("CreateBot", ctx, mock.Anything).Once().Return(&livechat.CreateBotResponse{ID: "abcd_2"}, nil)
+	lcHTTP.On("SetRoutingStatus", ctx, mock.Anything).Once().Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	agents := NewCollection()
+	agents.Register(NewAgent("abcd_1"))
+
+	_, err := Grow(ctx, lcHTTP, agents, nil)
+	assert.True(t, errors.Is(err, ErrAtCapacity))
+
+	agent, err := Grow(ctx, lcHTTP, agents, &Capacity{MaxChats: 1, MaxBots: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, livechat.AgentID("abcd_2"), agent.ID)
+	assert.Equal(t, 2, agents.Len())
+
+	_, err = Grow(ctx, lcHTTP, agents, &Capacity{MaxChats: 1, MaxBots: 2})
+	assert.True(t, errors.Is(err, ErrAtCapacity))
+	lcHTTP.AssertNumberOfCalls(t, "CreateBot", 1)
+}
+
 func Test_Terminate(t *testing.T) {
 	ctx := context.Background()
 	lcHTTP := new(mocks.LivechatRequests)
@@ -56,6 +100,26 @@ func Test_Terminate(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_Terminate_Failed(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("SetRoutingStatus", ctx, mock.MatchedBy(func(p *livechat.SetRoutingStatusRequest) bool {
+		return p.AgentID == "abcd_1"
+	})).Return(&livechat.SetRoutingStatusResponse{}, nil)
+	lcHTTP.On("SetRoutingStatus", ctx, mock.MatchedBy(func(p *livechat.SetRoutingStatusRequest) bool {
+		return p.AgentID == "abcd_2"
+	})).Return(nil, web.ErrAgentOffline)
+
+	agents := NewCollection()
+	agents.Register(NewAgent("abcd_1"))
+	agents.Register(NewAgent("abcd_2"))
+
+	err := Terminate(ctx, lcHTTP, agents)
+	assert.True(t, errors.Is(err, web.ErrAgentOffline))
+	lcHTTP.AssertNumberOfCalls(t, "SetRoutingStatus", 2)
+}
+
 func Test_CreateBot(t *testing.T) {
 	lcHTTP := new(mocks.LivechatRequests)
 	ctx := context.Background()
@@ -64,7 +128,7 @@ func Test_CreateBot(t *testing.T) {
 		On("CreateBot", ctx, mock.Anything).
 		Return(&livechat.CreateBotResponse{ID: livechat.AgentID("abcd")}, nil)
 
-	agent, err := createBot(ctx, lcHTTP)
+	agent, err := createBot(ctx, lcHTTP, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, livechat.AgentID("abcd"), agent.ID)
 }
@@ -76,13 +140,15 @@ func Test_FetchBots(t *testing.T) {
 	lcHTTP.
 		On("ListBots", ctx, mock.Anything).
 		Return([]*livechat.ListBotResponse{
-			{ID: "abcd_1"},
+			{ID: "abcd_1", MaxChatsCount: 3},
 			{ID: "abcd_2"},
 		}, nil)
 
-	agents, err := fetchBots(ctx, lcHTTP)
+	agents, err := fetchBots(ctx, lcHTTP, nil)
 	assert.NoError(t, err)
-	assert.Equal(t, 2, agents.Len())
+	if assert.Len(t, agents, 2) {
+		assert.Equal(t, 3, agents[0].MaxChats)
+	}
 }
 
 func Test_RemoveBot(t *testing.T) {
@@ -0,0 +1,49 @@
+package agents
+
+import "github.com/livechat/onboarding/livechat"
+
+type Agents interface {
+	Register(*Agent) error
+	Unregister(livechat.AgentID) (*Agent, error)
+
+	Len() int
+	// Snapshot returns state of every agent (in order of registration)
+	// taken at one moment, it is not updated later.
+	Snapshot() []AgentSnapshot
+
+	FindByChat(livechat.ChatID) (*Agent, error)
+	FindByChatExclude(livechat.ChatID) (*Agent, error)
+	// FindFree returns the least loaded agent which is accepted, does not
+	// serve the chat yet and has not reached its MaxChats. ErrAtCapacity
+	// is returned if any accepted agent has been skipped due to its limit.
+	// Agents are accepted by their index in order of registration.
+	FindFree(chatID livechat.ChatID, accept func(index int) bool) (*Agent, error)
+	FindByID(livechat.AgentID) (*Agent, error)
+
+	// ForgetChat drops the chat from every agent, it reports
+	// whether any of them knew the chat.
+	ForgetChat(livechat.ChatID) bool
+}
+
+// AgentSnapshot is a copy of agent's state.
+type AgentSnapshot struct {
+	ID       livechat.AgentID
+	MaxChats int
+	Chats    []livechat.ChatID
+}
+
+func NewCollection() Agents {
+	return &collection{
+		agents: []*Agent{},
+		byID:   make(map[livechat.AgentID]*Agent),
+		byChat: make(map[livechat.ChatID]*Agent),
+	}
+}
+
+func NewAgent(id livechat.AgentID) *Agent {
+	return &Agent{
+		ID:      id,
+		chats:   make(map[livechat.ChatID]uint64),
+		removed: make(map[livechat.ChatID]struct{}),
+	}
+}
@@ -0,0 +1,127 @@
+package bot_rtm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/chats"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/auth"
+	"github.com/livechat/onboarding/livechat/rtm"
+	"github.com/livechat/onboarding/livechat/web"
+	log "github.com/sirupsen/logrus"
+)
+
+var errAppClosed = errors.New("bot: app has been closed")
+
+type app struct {
+	lcHTTP    web.LivechatRequests
+	licenseID livechat.LicenseID
+	chats     *chats.Handler
+
+	// muConn guards conn and closed, connection is opened again in
+	// background while app may be uninstalled.
+	muConn sync.Mutex
+	conn   rtm.LivechatRTM
+	closed bool
+	// closing is closed together with app, done once app stops listening.
+	closing chan struct{}
+	done    chan struct{}
+
+	muTokens sync.Mutex
+	tokens   *auth.TokenSource
+}
+
+func newApp(lcHTTP web.LivechatRequests, sender bot.Sender, router *routing.Router, capacity *agents.Capacity, id livechat.LicenseID) *app {
+	return &app{
+		lcHTTP:    lcHTTP,
+		licenseID: id,
+		chats:     chats.New(lcHTTP, sender, router, capacity, id),
+		closing:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Info returns state of app shown by admin API.
+func (a *app) Info() *bot.LicenseInfo {
+	return &bot.LicenseInfo{ID: a.licenseID, Bots: a.chats.Bots()}
+}
+
+// WithOAuth authorizes context with the token of app's license.
+func (a *app) WithOAuth(ctx context.Context) (context.Context, error) {
+	a.muTokens.Lock()
+	tokens := a.tokens
+	a.muTokens.Unlock()
+
+	if tokens == nil {
+		return ctx, fmt.Errorf("bot: app (license id: %v) is not authorized", a.licenseID)
+	}
+
+	return tokens.WithOAuth(ctx)
+}
+
+func (a *app) SetTokens(tokens *auth.TokenSource) {
+	a.muTokens.Lock()
+	defer a.muTokens.Unlock()
+
+	a.tokens = tokens
+}
+
+// Attach makes app listen to conn. Closed app does not take connection,
+// so it has to be closed by the caller.
+func (a *app) Attach(conn rtm.LivechatRTM) error {
+	a.muConn.Lock()
+	defer a.muConn.Unlock()
+
+	if a.closed {
+		return fmt.Errorf("%w (license id: %v)", errAppClosed, a.licenseID)
+	}
+	a.conn = conn
+	return nil
+}
+
+func (a *app) connection() rtm.LivechatRTM {
+	a.muConn.Lock()
+	defer a.muConn.Unlock()
+
+	return a.conn
+}
+
+// Listen reads pushes from RTM connection until it is closed and passes
+// every one of them into handle function, which must not block.
+func (a *app) Listen(handle func(livechat.Push)) {
+	for push := range a.connection().Pushes() {
+		handle(push)
+	}
+
+	log.WithField("license_id", a.licenseID).Debug("RTM connection closed")
+}
+
+// Close disables bots and closes connection of app, errors of both
+// are returned together.
+func (a *app) Close(ctx context.Context) error {
+	a.muConn.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.closing)
+	}
+	conn := a.conn
+	a.muConn.Unlock()
+
+	err := agents.Terminate(ctx, a.lcHTTP, a.chats.Agents())
+	if conn == nil {
+		return err
+	}
+
+	err = errors.Join(err, conn.Close())
+	select {
+	case <-a.done:
+	case <-ctx.Done():
+	}
+	return err
+}
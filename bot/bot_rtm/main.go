@@ -0,0 +1,47 @@
+package bot_rtm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/queue"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/auth"
+	"github.com/livechat/onboarding/livechat/rtm"
+	"github.com/livechat/onboarding/livechat/web"
+)
+
+type Manager interface {
+	bot.BotManager
+	Redirect(context.Context, livechat.Push) error
+}
+
+// Dialer opens a new RTM connection for license.
+type Dialer func(ctx context.Context, url string, licenseID livechat.LicenseID) (rtm.LivechatRTM, error)
+
+// New creates manager of RTM bots. Every bot may take every chat if router
+// is nil, bots take any number of chats if capacity is nil. Pushes are
+// handled by jobs, off the goroutine reading the connection.
+func New(lcHTTP web.LivechatRequests, sender bot.Sender, router *routing.Router, capacity *agents.Capacity, jobs *queue.Queue, wsURL string) Manager {
+	return NewWithDialer(lcHTTP, rtm.Dial, sender, router, capacity, jobs, wsURL)
+}
+
+func NewWithDialer(lcHTTP web.LivechatRequests, dial Dialer, sender bot.Sender, router *routing.Router, capacity *agents.Capacity, jobs *queue.Queue, wsURL string) Manager {
+	return &manager{
+		lcHTTP:         lcHTTP,
+		dial:           dial,
+		wsURL:          wsURL,
+		jobs:           jobs,
+		reconnectDelay: time.Second,
+		apps:           make(map[livechat.LicenseID]*app),
+		sender:         sender,
+		router:         router,
+		capacity:       capacity,
+		tokens:         auth.NewRegistry(),
+		muApps:         &sync.Mutex{},
+	}
+}
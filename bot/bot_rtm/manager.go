@@ -0,0 +1,343 @@
+package bot_rtm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/queue"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/auth"
+	"github.com/livechat/onboarding/livechat/rtm"
+	"github.com/livechat/onboarding/livechat/web"
+	"github.com/livechat/onboarding/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// reconnectAttempts limits how many times dropped connection is opened
+// again before app is unregistered, maxReconnectDelay limits backoff.
+const (
+	reconnectAttempts = 5
+	maxReconnectDelay = 30 * time.Second
+)
+
+type manager struct {
+	lcHTTP web.LivechatRequests
+	dial   Dialer
+	wsURL  string
+	// jobs handles pushes, so reading connection is never blocked by them.
+	jobs *queue.Queue
+	// reconnectDelay is the wait before the first attempt to open dropped
+	// connection again, it is doubled after every failed attempt.
+	reconnectDelay time.Duration
+
+	muApps *sync.Mutex
+	apps   map[livechat.LicenseID]*app
+	sender bot.Sender
+	router *routing.Router
+	// capacity limits load of bots of every license.
+	capacity *agents.Capacity
+
+	tokens *auth.Registry
+}
+
+func (m *manager) Authorize(ctx context.Context, client livechat.Client, data *auth.AuthorizeCredentials) error {
+	id, err := m.tokens.Authorize(ctx, client, data)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("license_id", id).Debug("License authorized")
+	return nil
+}
+
+func (m *manager) InstallApp(ctx context.Context, id livechat.LicenseID) error {
+	m.muApps.Lock()
+	if _, ok := m.apps[id]; ok {
+		m.muApps.Unlock()
+		return fmt.Errorf("bot: app (license id: %v) is already installed", id)
+	}
+	app := newApp(m.lcHTTP, m.sender, m.router, m.capacity, id)
+	m.apps[id] = app
+	m.muApps.Unlock()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	tokens, err := m.tokens.Wait(waitCtx, id)
+	cancel()
+	if err != nil {
+		m.unregister(id)
+		return fmt.Errorf("bot: license (id: %v) has not been authorized: %w", id, err)
+	}
+	app.SetTokens(tokens)
+	log.WithField("license_id", id).Debug("App is ready to be installed!")
+
+	authCtx, err := app.WithOAuth(ctx)
+	if err != nil {
+		m.unregister(id)
+		return err
+	}
+
+	bots, err := agents.Initialize(authCtx, m.lcHTTP, m.capacity)
+	if err != nil {
+		m.unregister(id)
+		return err
+	}
+	app.chats.SetAgents(bots)
+
+	conn, err := m.connect(ctx, app)
+	if err != nil {
+		m.unregister(id)
+		return err
+	}
+	// App might have been uninstalled while connection was being opened.
+	if err := app.Attach(conn); err != nil {
+		conn.Close()
+		return err
+	}
+	go m.listen(app)
+
+	log.WithField("license_id", id).Debug("RTM connection established")
+	return nil
+}
+
+// connect opens RTM connection of app and logs in with the token of its license.
+func (m *manager) connect(ctx context.Context, app *app) (rtm.LivechatRTM, error) {
+	ctx, err := app.WithOAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := auth.GetAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := m.dial(ctx, m.wsURL, app.licenseID)
+	if err != nil {
+		log.WithField("license_id", app.licenseID).WithError(err).Error("Cannot open RTM connection")
+		return nil, err
+	}
+	if _, err := conn.Login(ctx, &rtm.LoginRequest{Token: token}); err != nil {
+		log.WithField("license_id", app.licenseID).WithError(err).Error("Cannot log in to RTM API")
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// listen queues pushes of app until it is closed. Interrupted connection
+// is opened again, app is unregistered if it cannot be.
+func (m *manager) listen(app *app) {
+	var err error
+	for err == nil {
+		app.Listen(m.enqueue)
+		err = m.reconnect(app)
+	}
+	close(app.done)
+
+	if errors.Is(err, errAppClosed) || !m.unregisterApp(app) {
+		return
+	}
+	log.WithField("license_id", app.licenseID).WithError(err).Error("Cannot reconnect to RTM API, app has been unregistered")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx, _ = app.WithOAuth(ctx)
+	app.Close(ctx)
+	m.jobs.Remove(app.licenseID)
+}
+
+// reconnect opens dropped connection of app again, waiting longer
+// after every failed attempt.
+func (m *manager) reconnect(app *app) error {
+	delay := m.reconnectDelay
+
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		select {
+		case <-app.closing:
+			return errAppClosed
+		case <-time.After(delay):
+		}
+		log.WithField("license_id", app.licenseID).WithField("attempt", attempt).Warn("Reconnecting to RTM API")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		conn, connErr := m.connect(ctx, app)
+		cancel()
+		if connErr == nil {
+			if err := app.Attach(conn); err != nil {
+				conn.Close()
+				return err
+			}
+			return nil
+		}
+
+		err = connErr
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+
+	return fmt.Errorf("bot: reconnect: %w", err)
+}
+
+// enqueue passes push to worker of its chat. Pushes are dropped if the
+// worker is too far behind, RTM API does not deliver them again.
+func (m *manager) enqueue(push livechat.Push) {
+	logEntry := log.WithFields(log.Fields{
+		"license_id": push.GetLicenseID(),
+		"chat_id":    push.GetChatID(),
+		"action":     push.GetAction(),
+	})
+
+	err := m.jobs.Push(push.GetLicenseID(), push.GetChatID(), func(ctx context.Context) {
+		if err := m.Redirect(ctx, push); err != nil {
+			logEntry.WithError(err).Error("Cannot handle RTM push")
+		}
+	})
+	if err != nil {
+		metrics.RejectedPushes.WithLabelValues(strconv.Itoa(int(push.GetLicenseID()))).Inc()
+		logEntry.WithError(err).Warn("Dropped RTM push")
+	}
+}
+
+func (m *manager) UninstallApp(ctx context.Context, id livechat.LicenseID) error {
+	app := m.unregister(id)
+	if app == nil {
+		return fmt.Errorf("bot: app (license id: %v) is not registered", id)
+	}
+
+	defer m.tokens.Delete(id)
+	defer m.jobs.Remove(id)
+
+	ctx, err := app.WithOAuth(ctx)
+	if err != nil {
+		log.WithField("license_id", id).WithError(err).Warn("Closing RTM connection without authorization")
+	}
+
+	return app.Close(ctx)
+}
+
+func (m *manager) Destroy(ctx context.Context) {
+	wg := &sync.WaitGroup{}
+
+	m.muApps.Lock()
+	ids := make([]livechat.LicenseID, 0, len(m.apps))
+	for id := range m.apps {
+		ids = append(ids, id)
+	}
+	m.muApps.Unlock()
+
+	for _, id := range ids {
+		wg.Add(1)
+
+		go func(id livechat.LicenseID) {
+			defer wg.Done()
+			m.UninstallApp(ctx, id)
+		}(id)
+	}
+
+	wg.Wait()
+}
+
+func (m *manager) Redirect(ctx context.Context, rawMsg livechat.Push) (err error) {
+	metrics.PushesReceived.WithLabelValues(rawMsg.GetAction(), strconv.Itoa(int(rawMsg.GetLicenseID()))).Inc()
+	defer func() {
+		if err != nil {
+			metrics.RedirectErrors.WithLabelValues(rawMsg.GetAction()).Inc()
+		}
+	}()
+
+	app, err := m.find(rawMsg.GetLicenseID())
+	if err != nil {
+		return fmt.Errorf("bot: redirect_action: %w", err)
+	}
+
+	ctx, err = app.WithOAuth(ctx)
+	if err != nil {
+		return fmt.Errorf("bot: redirect_action: %w", err)
+	}
+
+	return app.chats.Handle(ctx, rawMsg)
+}
+
+func (m *manager) Licenses() []*bot.LicenseInfo {
+	m.muApps.Lock()
+	defer m.muApps.Unlock()
+
+	licenses := []*bot.LicenseInfo{}
+	for _, app := range m.apps {
+		licenses = append(licenses, app.Info())
+	}
+	sort.Slice(licenses, func(i, j int) bool { return licenses[i].ID < licenses[j].ID })
+	return licenses
+}
+
+func (m *manager) Authorized() []livechat.LicenseID {
+	return m.tokens.Licenses()
+}
+
+func (m *manager) License(id livechat.LicenseID) (*bot.LicenseInfo, error) {
+	app, err := m.find(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w (license id: %v)", bot.ErrLicenseNotFound, id)
+	}
+	return app.Info(), nil
+}
+
+func (m *manager) ReleaseChat(ctx context.Context, id livechat.LicenseID, chatID livechat.ChatID) error {
+	app, err := m.find(id)
+	if err != nil {
+		return fmt.Errorf("%w (license id: %v)", bot.ErrLicenseNotFound, id)
+	}
+
+	ctx, err = app.WithOAuth(ctx)
+	if err != nil {
+		return fmt.Errorf("bot: release_chat: %w", err)
+	}
+
+	return app.chats.ReleaseChat(ctx, chatID)
+}
+
+func (m *manager) find(id livechat.LicenseID) (*app, error) {
+	m.muApps.Lock()
+	defer m.muApps.Unlock()
+
+	app, ok := m.apps[id]
+	if !ok {
+		return nil, fmt.Errorf("bot: app (license id: %v) is not installed", id)
+	}
+	return app, nil
+}
+
+func (m *manager) unregister(id livechat.LicenseID) *app {
+	m.muApps.Lock()
+	defer m.muApps.Unlock()
+
+	app, ok := m.apps[id]
+	if !ok {
+		return nil
+	}
+	delete(m.apps, id)
+	return app
+}
+
+// unregisterApp unregisters app unless another app of the same license
+// has been installed in its place.
+func (m *manager) unregisterApp(app *app) bool {
+	m.muApps.Lock()
+	defer m.muApps.Unlock()
+
+	if m.apps[app.licenseID] != app {
+		return false
+	}
+	delete(m.apps, app.licenseID)
+	return true
+}
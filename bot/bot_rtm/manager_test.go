@@ -0,0 +1,266 @@
+package bot_rtm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/flow"
+	"github.com/livechat/onboarding/bot/handoff"
+	"github.com/livechat/onboarding/bot/queue"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/auth"
+	lcMocks "github.com/livechat/onboarding/livechat/mocks"
+	"github.com/livechat/onboarding/livechat/rtm"
+	rtmMocks "github.com/livechat/onboarding/livechat/rtm/mocks"
+	"github.com/livechat/onboarding/livechat/web/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	validLicenseID   = livechat.LicenseID(23456)
+	invalidLicenseID = livechat.LicenseID(12345)
+
+	validChatID = livechat.ChatID("chat_id_1")
+	validBotID  = livechat.AgentID("bot_1")
+
+	oauthToken = "some_random_token"
+)
+
+var matchCtx = mock.MatchedBy(mockContextWithOAuthToken)
+
+func Test_Manager_Install(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	pushes := make(chan livechat.Push)
+	defer close(pushes)
+
+	manager, conn := helperCreateManager(t, lcHTTP, pushes)
+	assert.Len(t, manager.apps, 1)
+	conn.AssertCalled(t, "Login", matchCtx, mock.MatchedBy(func(p *rtm.LoginRequest) bool {
+		return p.Token == fmt.Sprintf("Bearer %s", oauthToken)
+	}))
+}
+
+func Test_Manager_Uninstall_InvalidLicenseID(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	pushes := make(chan livechat.Push)
+	defer close(pushes)
+
+	manager, _ := helperCreateManager(t, lcHTTP, pushes)
+	assert.Error(t, manager.UninstallApp(context.Background(), invalidLicenseID))
+}
+
+func Test_Manager_Uninstall_ValidLicenseID(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	pushes := make(chan livechat.Push)
+
+	// +uninstall bots
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Once().Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	manager, conn := helperCreateManager(t, lcHTTP, pushes)
+	conn.On("Close").Run(func(mock.Arguments) { close(pushes) }).Once().Return(nil)
+
+	assert.NoError(t, manager.UninstallApp(context.Background(), validLicenseID))
+	assert.Len(t, manager.apps, 0)
+	conn.AssertNumberOfCalls(t, "Close", 1)
+}
+
+func Test_Manager_Install_UninstalledWhileConnecting(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	pushes := make(chan livechat.Push)
+	defer close(pushes)
+
+	// +uninstall bots
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Once().Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	conn := helperCreateConn(t, pushes)
+	conn.On("Close").Once().Return(nil)
+
+	var manager *manager
+	manager = helperAuthorizeManager(t, lcHTTP, func(context.Context, string, livechat.LicenseID) (rtm.LivechatRTM, error) {
+		assert.NoError(t, manager.UninstallApp(context.Background(), validLicenseID))
+		return conn, nil
+	})
+
+	assert.True(t, errors.Is(manager.InstallApp(context.Background(), validLicenseID), errAppClosed))
+	assert.Empty(t, manager.Licenses())
+	conn.AssertNumberOfCalls(t, "Close", 1)
+}
+
+func Test_Manager_Reconnect(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	// nextPushes is never closed, so connection is not opened once again.
+	pushes, nextPushes := make(chan livechat.Push), make(chan livechat.Push)
+
+	transferred := make(chan bool, 1)
+	lcHTTP.On("TransferChat", matchCtx, mock.Anything).Once().Run(func(mock.Arguments) { transferred <- true }).Return(&livechat.TransferChatResponse{}, nil)
+
+	manager, _ := helperCreateManager(t, lcHTTP, pushes)
+	next := helperCreateConn(t, nextPushes)
+	manager.dial = func(context.Context, string, livechat.LicenseID) (rtm.LivechatRTM, error) {
+		return next, nil
+	}
+	manager.reconnectDelay = time.Millisecond
+	close(pushes)
+
+	push := &livechat.PushIncomingChat{Action: "incoming_chat", LicenseID: validLicenseID}
+	push.Payload.Chat.ID = validChatID
+	select {
+	case nextPushes <- push:
+	case <-time.After(time.Second):
+		t.Fatalf("connection has not been opened again")
+	}
+
+	select {
+	case <-transferred:
+	case <-time.After(time.Second):
+		t.Fatalf("chat has not been transferred")
+	}
+	next.AssertCalled(t, "Login", matchCtx, mock.Anything)
+}
+
+func Test_Manager_Reconnect_Failed(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	pushes := make(chan livechat.Push)
+
+	terminated := make(chan bool, 1)
+	manager, conn := helperCreateManager(t, lcHTTP, pushes)
+	conn.On("Close").Return(nil)
+	// +uninstall bots
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Once().Run(func(mock.Arguments) { terminated <- true }).Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	manager.dial = func(context.Context, string, livechat.LicenseID) (rtm.LivechatRTM, error) {
+		return nil, errors.New("connection refused")
+	}
+	manager.reconnectDelay = time.Millisecond
+	close(pushes)
+
+	select {
+	case <-terminated:
+	case <-time.After(time.Second):
+		t.Fatalf("bots have not been terminated")
+	}
+	_, err := manager.License(validLicenseID)
+	assert.True(t, errors.Is(err, bot.ErrLicenseNotFound))
+}
+
+func Test_Manager_Pushes_IncomingChat(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	pushes := make(chan livechat.Push)
+
+	transferred := make(chan bool, 1)
+	lcHTTP.On("TransferChat", matchCtx, mock.MatchedBy(func(p *livechat.TransferChatRequest) bool {
+		return p.ID == validChatID
+	})).Once().Run(func(mock.Arguments) { transferred <- true }).Return(&livechat.TransferChatResponse{}, nil)
+
+	helperCreateManager(t, lcHTTP, pushes)
+
+	push := &livechat.PushIncomingChat{Action: "incoming_chat", LicenseID: validLicenseID}
+	push.Payload.Chat.ID = validChatID
+	pushes <- push
+	close(pushes)
+
+	select {
+	case <-transferred:
+	case <-time.After(time.Second):
+		t.Fatalf("chat has not been transferred")
+	}
+}
+
+func Test_Manager_ReleaseChat(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	pushes := make(chan livechat.Push)
+	defer close(pushes)
+
+	lcHTTP.On("TransferChat", matchCtx, mock.Anything).Once().Return(&livechat.TransferChatResponse{}, nil)
+	lcHTTP.On("RemoveUserFromChat", matchCtx, mock.MatchedBy(func(p *livechat.RemoveUserFromChatRequest) bool {
+		return p.ChatID == validChatID && p.UserID == validBotID
+	})).Once().Return(&livechat.RemoveUserFromChatResponse{}, nil)
+
+	manager, _ := helperCreateManager(t, lcHTTP, pushes)
+	push := &livechat.PushIncomingChat{Action: "incoming_chat", LicenseID: validLicenseID}
+	push.Payload.Chat.ID = validChatID
+	assert.NoError(t, manager.Redirect(context.Background(), push))
+
+	licenses := manager.Licenses()
+	if assert.Len(t, licenses, 1) {
+		assert.Equal(t, []livechat.ChatID{validChatID}, licenses[0].Bots[0].Chats)
+	}
+
+	assert.NoError(t, manager.ReleaseChat(context.Background(), validLicenseID, validChatID))
+	license, _ := manager.License(validLicenseID)
+	assert.Empty(t, license.Bots[0].Chats)
+
+	err := manager.ReleaseChat(context.Background(), invalidLicenseID, validChatID)
+	assert.True(t, errors.Is(err, bot.ErrLicenseNotFound))
+}
+
+func helperCreateManager(t *testing.T, lcHTTP *mocks.LivechatRequests, pushes chan livechat.Push) (*manager, *rtmMocks.LivechatRTM) {
+	t.Helper()
+
+	conn := helperCreateConn(t, pushes)
+	manager := helperAuthorizeManager(t, lcHTTP, func(context.Context, string, livechat.LicenseID) (rtm.LivechatRTM, error) {
+		return conn, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	if err := manager.InstallApp(ctx, validLicenseID); err != nil {
+		t.Fatalf("cannot create manager: %s", err.Error())
+	}
+
+	return manager, conn
+}
+
+func helperCreateConn(t *testing.T, pushes chan livechat.Push) *rtmMocks.LivechatRTM {
+	t.Helper()
+
+	conn := new(rtmMocks.LivechatRTM)
+	conn.On("Login", matchCtx, mock.Anything).Once().Return(&rtm.LoginResponse{}, nil)
+	conn.On("Pushes").Return((<-chan livechat.Push)(pushes))
+	return conn
+}
+
+// helperAuthorizeManager creates manager with authorized license, dropped
+// connections are not opened again unless test shortens reconnectDelay.
+func helperAuthorizeManager(t *testing.T, lcHTTP *mocks.LivechatRequests, dial Dialer) *manager {
+	t.Helper()
+	// +install bots
+	lcHTTP.On("CreateBot", matchCtx, mock.Anything).Once().Return(&livechat.CreateBotResponse{ID: validBotID}, nil)
+	lcHTTP.On("ListBots", matchCtx, mock.Anything).Once().Return([]*livechat.ListBotResponse{{ID: validBotID}}, nil)
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Once().Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	byteBody, _ := json.Marshal(map[string]interface{}{"access_token": oauthToken, "license_id": validLicenseID})
+	httpClient := new(lcMocks.Client)
+	httpClient.On("Do", mock.Anything).Once().Return(&http.Response{
+		Body:       io.NopCloser(bytes.NewBuffer(byteBody)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	conversation, _ := flow.New(flow.Default())
+	transfer, _ := handoff.New(lcHTTP, &handoff.Config{})
+	jobs := queue.New(context.Background(), nil)
+	mng := NewWithDialer(lcHTTP, dial, bot.NewSender(lcHTTP, "author_id", conversation, transfer), nil, nil, jobs, "ws://localhost").(*manager)
+	mng.reconnectDelay = time.Hour
+	assert.NoError(t, mng.Authorize(context.Background(), httpClient, &auth.AuthorizeCredentials{}))
+
+	return mng
+}
+
+func mockContextWithOAuthToken(ctx context.Context) bool {
+	token, err := auth.GetAuthToken(ctx)
+	if err != nil {
+		return false
+	}
+
+	return token == fmt.Sprintf("Bearer %s", oauthToken)
+}
@@ -2,12 +2,18 @@ package bot_webhooks
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"strings"
 	"sync"
 
 	"github.com/livechat/onboarding/bot"
-	"github.com/livechat/onboarding/bot/bot_webhooks/agents"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/chats"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/bot/store"
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/auth"
 	"github.com/livechat/onboarding/livechat/web"
@@ -16,32 +22,138 @@ import (
 
 type app struct {
 	lcHTTP    web.LivechatRequests
-	sender    bot.Sender
 	licenseID livechat.LicenseID
-	agents    agents.Agents
-	webhooks  map[string]*webhookDetails
+	chats     *chats.Handler
 	localURL  string
+	secretKey string
+
+	// muWebhooks guards webhooks, they are registered while the app may
+	// already be read by admin API and metrics.
+	muWebhooks sync.Mutex
+	webhooks   map[string]*webhookDetails
+
+	muTokens sync.Mutex
+	tokens   *auth.TokenSource
+
+	// muPersist makes sure snapshots of app are taken and saved one
+	// at a time, so an older snapshot never overwrites a newer one.
+	muPersist sync.Mutex
 }
 
 type webhookDetails struct {
 	id string
 }
 
-func newApp(lcHTTP web.LivechatRequests, sender bot.Sender, id livechat.LicenseID, localURL string) *app {
+func newApp(lcHTTP web.LivechatRequests, sender bot.Sender, router *routing.Router, capacity *agents.Capacity, id livechat.LicenseID, localURL string) (*app, error) {
+	secretKey, err := generateSecretKey()
+	if err != nil {
+		return nil, fmt.Errorf("bot: new_app: %w", err)
+	}
+
 	return &app{
 		lcHTTP:    lcHTTP,
 		licenseID: id,
-		agents:    agents.NewCollection(),
+		chats:     chats.New(lcHTTP, sender, router, capacity, id),
+		webhooks:  make(map[string]*webhookDetails),
+		localURL:  localURL,
+		secretKey: secretKey,
+	}, nil
+}
+
+// restoreApp recreates app from snapshot saved in store.
+func restoreApp(lcHTTP web.LivechatRequests, sender bot.Sender, router *routing.Router, capacity *agents.Capacity, license *store.License, localURL string) *app {
+	a := &app{
+		lcHTTP:    lcHTTP,
+		licenseID: license.ID,
+		chats:     chats.New(lcHTTP, sender, router, capacity, license.ID),
 		webhooks:  make(map[string]*webhookDetails),
 		localURL:  localURL,
-		sender:    sender,
+		secretKey: license.SecretKey,
+	}
+
+	for action, id := range license.Webhooks {
+		a.webhooks[action] = &webhookDetails{id: id}
+	}
+	for _, b := range license.Bots {
+		agent := agents.NewAgent(b.ID)
+		agent.MaxChats = capacity.ChatsLimit(b.MaxChats)
+		agent.RegisterChat(b.Chats...)
+		a.chats.Agents().Register(agent)
 	}
+
+	return a
+}
+
+// WithOAuth authorizes context with the token of app's license.
+func (a *app) WithOAuth(ctx context.Context) (context.Context, error) {
+	a.muTokens.Lock()
+	tokens := a.tokens
+	a.muTokens.Unlock()
+
+	if tokens == nil {
+		return ctx, fmt.Errorf("bot: app (license id: %v) is not authorized", a.licenseID)
+	}
+
+	return tokens.WithOAuth(ctx)
+}
+
+func (a *app) SetTokens(tokens *auth.TokenSource) {
+	a.muTokens.Lock()
+	defer a.muTokens.Unlock()
+
+	a.tokens = tokens
+}
+
+// Snapshot returns state of app which should survive restart.
+func (a *app) Snapshot() *store.License {
+	license := &store.License{
+		ID:        a.licenseID,
+		SecretKey: a.secretKey,
+		Webhooks:  make(map[string]string),
+		Bots:      []*store.Bot{},
+	}
+	a.muWebhooks.Lock()
+	for action, details := range a.webhooks {
+		license.Webhooks[action] = details.id
+	}
+	a.muWebhooks.Unlock()
+
+	for _, agent := range a.chats.Agents().Snapshot() {
+		license.Bots = append(license.Bots, &store.Bot{ID: agent.ID, MaxChats: agent.MaxChats, Chats: agent.Chats})
+	}
+
+	a.muTokens.Lock()
+	tokens := a.tokens
+	a.muTokens.Unlock()
+	if tokens != nil {
+		token := tokens.Current()
+		license.Token = &store.Token{AccessToken: token.AccessToken, RefreshToken: token.RefreshToken, Expiry: token.Expiry}
+	}
+
+	return license
+}
+
+// Info returns state of app shown by admin API.
+func (a *app) Info() *bot.LicenseInfo {
+	snapshot := a.Snapshot()
+
+	info := &bot.LicenseInfo{ID: snapshot.ID, Webhooks: snapshot.Webhooks, Bots: []*bot.BotInfo{}}
+	for _, agent := range snapshot.Bots {
+		info.Bots = append(info.Bots, &bot.BotInfo{ID: agent.ID, Chats: agent.Chats})
+	}
+	return info
+}
+
+// VerifySecretKey checks (in constant time) whether the key sent
+// with webhook matches the one registered for this license.
+func (a *app) VerifySecretKey(secretKey string) bool {
+	return subtle.ConstantTimeCompare([]byte(a.secretKey), []byte(secretKey)) == 1
 }
 
 func (a *app) RegisterAction(ctx context.Context, actions ...string) error {
 	for _, action := range actions {
 		payload := &livechat.RegisterWebhookRequest{
-			SecretKey: "random secret key",
+			SecretKey: a.secretKey,
 			URL:       fmt.Sprintf("%s/webhooks/%s", a.localURL, action),
 			Action:    action,
 			Type:      "license",
@@ -55,21 +167,37 @@ func (a *app) RegisterAction(ctx context.Context, actions ...string) error {
 
 		log.WithField("action", action).WithField("url", fmt.Sprintf("%s/webhooks/%s", a.localURL, action)).Debug("Webhook registered")
 
+		a.muWebhooks.Lock()
 		a.webhooks[action] = &webhookDetails{id: webhookResponse.ID}
+		a.muWebhooks.Unlock()
 	}
 
 	return nil
 }
 
+// UnregisterActions unregisters every webhook and disables bots, errors
+// of all of them are returned together.
 func (a *app) UnregisterActions(ctx context.Context) error {
+	var (
+		muErrs sync.Mutex
+		errs   []error
+	)
+	fail := func(err error) {
+		muErrs.Lock()
+		defer muErrs.Unlock()
+		errs = append(errs, err)
+	}
+
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		agents.Terminate(ctx, a.lcHTTP, a.agents)
+		if err := agents.Terminate(ctx, a.lcHTTP, a.chats.Agents()); err != nil {
+			fail(err)
+		}
 	}()
 
-	for actionName, details := range a.webhooks {
+	for actionName, details := range a.takeWebhooks() {
 		wg.Add(1)
 
 		go func(aName string, d *webhookDetails) {
@@ -79,6 +207,7 @@ func (a *app) UnregisterActions(ctx context.Context) error {
 
 			if err != nil {
 				logEntry.WithError(err).Error("Cannot unregister webhook")
+				fail(fmt.Errorf("bot: unregister %s webhook: %w", aName, err))
 			} else {
 				logEntry.Debug("Webhook unregistered")
 			}
@@ -86,58 +215,43 @@ func (a *app) UnregisterActions(ctx context.Context) error {
 	}
 
 	wg.Wait()
-	return nil
+	return errors.Join(errs...)
 }
 
-func (a *app) TransferChat(ctx context.Context, msg *livechat.PushIncomingChat) error {
-	agent, err := a.agents.FindByChatExclude(msg.Payload.Chat.ID)
-	if err != nil {
-		return fmt.Errorf("bot: transfer_chat action: %w", err)
+// Reinstall registers webhooks and enables bots of restored app again.
+// Webhooks saved before restart are unregistered first, but they might
+// have been removed already, so errors are only logged.
+func (a *app) Reinstall(ctx context.Context) error {
+	for action, details := range a.takeWebhooks() {
+		if _, err := a.lcHTTP.UnregisterWebhook(ctx, &livechat.UnregisterWebhookRequest{ID: details.id}); err != nil {
+			log.WithField("license_id", a.licenseID).WithField("action", action).WithError(err).Debug("Cannot unregister stale webhook")
+		}
 	}
 
-	if _, err = a.lcHTTP.TransferChat(ctx, buildTransferChatMessage(msg.Payload.Chat.ID, agent.ID)); !isTransferChatErrorOk(err) {
-		return fmt.Errorf("bot: transfer_chat action: %w", err)
+	if err := a.RegisterAction(ctx, WebhookEvents...); err != nil {
+		return err
 	}
-
-	return agent.RegisterChat(msg.Payload.Chat.ID)
-}
-
-func (a *app) IncomingEvent(ctx context.Context, msg *livechat.PushIncomingMessage) error {
-	agent, err := a.agents.FindByChat(msg.Payload.ChatID)
-	if err != nil {
-		return nil
+	if _, err := a.lcHTTP.EnableLicenseWebhook(ctx, &livechat.EnableLicenseWebhookRequest{}); err != nil {
+		return fmt.Errorf("bot: reinstall: %w", err)
 	}
 
-	return a.sender.Talk(auth.WithAuthorID(ctx, agent.ID), msg.Payload.ChatID, msg)
+	return agents.Enable(ctx, a.lcHTTP, a.chats.Agents())
 }
 
-func (a *app) UserAddedToChat(ctx context.Context, msg *livechat.PushUserAddedToChat) error {
-	agent, err := a.agents.FindByChat(msg.Payload.ChatID)
-	if err != nil {
-		return nil
-	}
-
-	if msg.Payload.User.Present && msg.Payload.User.Type == "agent" {
-		return agent.UnregisterChat(msg.Payload.ChatID)
-	}
+// takeWebhooks returns registered webhooks and forgets them.
+func (a *app) takeWebhooks() map[string]*webhookDetails {
+	a.muWebhooks.Lock()
+	defer a.muWebhooks.Unlock()
 
-	return nil
+	webhooks := a.webhooks
+	a.webhooks = make(map[string]*webhookDetails)
+	return webhooks
 }
 
-func buildTransferChatMessage(chatID livechat.ChatID, agentID livechat.AgentID) *livechat.TransferChatRequest {
-	return &livechat.TransferChatRequest{
-		ID: chatID,
-		Target: struct {
-			Type string             "json:\"type\""
-			IDs  []livechat.AgentID "json:\"ids\""
-		}{
-			Type: "agent",
-			IDs:  []livechat.AgentID{agentID},
-		},
-		Force: false,
+func generateSecretKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-}
-
-func isTransferChatErrorOk(err error) bool {
-	return err == nil || strings.Contains(err.Error(), "One or more of requested agents are already present in the chat")
+	return hex.EncodeToString(b), nil
 }
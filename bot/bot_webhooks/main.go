@@ -2,10 +2,13 @@ package bot_webhooks
 
 import (
 	"context"
-	"sync"
 
 	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/bot/store"
 	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/auth"
 	"github.com/livechat/onboarding/livechat/web"
 )
 
@@ -13,20 +16,34 @@ var WebhookEvents = []string{
 	"incoming_chat",
 	"incoming_event",
 	"user_added_to_chat",
+	"incoming_rich_message_postback",
+	"chat_deactivated",
+	"user_removed_from_chat",
+	"chat_transferred",
 }
 
 type Manager interface {
 	bot.BotManager
 	Redirect(context.Context, livechat.Push) error
+	// VerifySecretKey returns an error if secret key does not belong
+	// to the installed license.
+	VerifySecretKey(livechat.LicenseID, string) error
+	// Restore loads apps saved in store during previous run. Saved tokens
+	// are refreshed with given client and credentials.
+	Restore(context.Context, livechat.Client, *auth.AuthorizeCredentials) error
 }
 
-func New(lcHTTP web.LivechatRequests, localURL string, authorID string) Manager {
+// New creates manager of webhook bots. Every bot may take every chat if router
+// is nil, bots take any number of chats if capacity is nil.
+func New(lcHTTP web.LivechatRequests, sender bot.Sender, router *routing.Router, capacity *agents.Capacity, store store.Store, localURL string) Manager {
 	return &manager{
-		lcHTTP:         lcHTTP,
-		localURL:       localURL,
-		apps:           &apps{},
-		sender:         bot.NewSender(lcHTTP, authorID),
-		readyToInstall: make(chan bool, 1),
-		muAuth:         &sync.Mutex{},
+		lcHTTP:   lcHTTP,
+		localURL: localURL,
+		apps:     &apps{},
+		sender:   sender,
+		router:   router,
+		capacity: capacity,
+		tokens:   auth.NewRegistry(),
+		store:    store,
 	}
 }
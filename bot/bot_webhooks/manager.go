@@ -4,14 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/livechat/onboarding/bot"
-	"github.com/livechat/onboarding/bot/bot_webhooks/agents"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/chats"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/bot/store"
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/auth"
 	"github.com/livechat/onboarding/livechat/web"
+	"github.com/livechat/onboarding/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,56 +26,55 @@ type manager struct {
 
 	apps   *apps
 	sender bot.Sender
-
-	muAuth         *sync.Mutex
-	authToken      string
-	readyToInstall chan bool
+	router *routing.Router
+	// capacity limits load of bots of every license.
+	capacity *agents.Capacity
+	tokens   *auth.Registry
+	store    store.Store
 }
 
 func (m *manager) Authorize(ctx context.Context, client livechat.Client, data *auth.AuthorizeCredentials) error {
-	m.muAuth.Lock()
-	defer m.muAuth.Unlock()
-
-	if m.authToken != "" {
-		m.authToken = ""
-		m.readyToInstall = make(chan bool, 1)
-	}
-
-	response, err := auth.Authorize(ctx, client, data)
+	id, err := m.tokens.Authorize(ctx, client, data)
 	if err != nil {
 		return err
 	}
 
-	m.authToken = response.AccessToken
-	m.readyToInstall <- true
-	close(m.readyToInstall)
-
+	log.WithField("license_id", id).Debug("License authorized")
 	return nil
 }
 
 func (m *manager) InstallApp(ctx context.Context, id livechat.LicenseID) error {
-	app := newApp(m.lcHTTP, m.sender, id, m.localURL)
-	m.apps.Register(app)
-
-	if m.authToken == "" {
-		select {
-		case <-m.readyToInstall:
-			log.Debug("App is ready to be installed!")
-			break
-		case <-time.After(30 * time.Second):
-			return errors.New("timeout")
-		case <-ctx.Done():
-			return ctx.Err()
-		}
+	app, err := newApp(m.lcHTTP, m.sender, m.router, m.capacity, id, m.localURL)
+	if err != nil {
+		return err
+	}
+	if err := m.apps.Register(app); err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	tokens, err := m.tokens.Wait(waitCtx, id)
+	cancel()
+	if err != nil {
+		m.apps.Unregister(id)
+		return fmt.Errorf("bot: license (id: %v) has not been authorized: %w", id, err)
+	}
+	m.setTokens(app, tokens)
+	log.WithField("license_id", id).Debug("App is ready to be installed!")
+
+	ctx, err = app.WithOAuth(ctx)
+	if err != nil {
+		m.apps.Unregister(id)
+		return err
 	}
 
-	ctx = auth.WithOAuth(ctx, m.authToken)
-	bots, err := agents.Initialize(ctx, m.lcHTTP)
+	bots, err := agents.Initialize(ctx, m.lcHTTP, m.capacity)
 	if err != nil {
+		m.apps.Unregister(id)
 		return err
 	}
 
-	app.agents = bots
+	app.chats.SetAgents(bots)
 
 	if err := app.RegisterAction(ctx, WebhookEvents...); err != nil {
 		log.WithField("license_id", id).WithError(err).Error("Cannot register 'incoming_chat' action")
@@ -83,68 +87,125 @@ func (m *manager) InstallApp(ctx context.Context, id livechat.LicenseID) error {
 		return err
 	}
 
+	m.persist(app)
 	return nil
 }
 
 func (m *manager) UninstallApp(ctx context.Context, id livechat.LicenseID) error {
+	defer m.tokens.Delete(id)
+
 	app := m.apps.Unregister(id)
 	if app == nil {
 		return fmt.Errorf("bot: app (license id: %v) is not registered", id)
 	}
+	defer m.forget(app)
 
-	defer func() {
-		m.authToken = ""
-		m.readyToInstall = make(chan bool, 1)
-	}()
-
-	ctx = auth.WithOAuth(ctx, m.authToken)
-	if _, err := app.lcHTTP.DisableLicenseWebhook(ctx, &livechat.DisableLicenseWebhookRequest{}); err != nil {
-		return err
-	}
-
-	return app.UnregisterActions(ctx)
+	return m.uninstall(ctx, app)
 }
 
+// Destroy uninstalls every app but (unlike UninstallApp) keeps their state
+// in store, so they can be restored and installed again after restart.
 func (m *manager) Destroy(ctx context.Context) {
 	wg := &sync.WaitGroup{}
-	ctx = auth.WithOAuth(ctx, m.authToken)
 
-	for _, app := range m.apps.apps {
+	for _, app := range m.apps.List() {
 		wg.Add(1)
 
 		go func(id livechat.LicenseID) {
 			defer wg.Done()
-			m.UninstallApp(ctx, id)
+			if app := m.apps.Unregister(id); app != nil {
+				m.uninstall(ctx, app)
+			}
 		}(app.licenseID)
 	}
 
 	wg.Wait()
 }
 
-func (m *manager) Redirect(ctx context.Context, rawMsg livechat.Push) error {
+// uninstall cleans up unregistered app in LiveChat.
+func (m *manager) uninstall(ctx context.Context, app *app) error {
+	ctx, err := app.WithOAuth(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Every step is done even if the previous one fails,
+	// so as little as possible is left in LiveChat.
+	_, err = app.lcHTTP.DisableLicenseWebhook(ctx, &livechat.DisableLicenseWebhookRequest{})
+	if err != nil {
+		err = fmt.Errorf("bot: disable webhooks: %w", err)
+	}
+
+	return errors.Join(err, app.UnregisterActions(ctx))
+}
+
+func (m *manager) VerifySecretKey(id livechat.LicenseID, secretKey string) error {
+	app, err := m.apps.Find(id)
+	if err != nil {
+		return fmt.Errorf("bot: verify_secret_key: %w", err)
+	}
+	if !app.VerifySecretKey(secretKey) {
+		return fmt.Errorf("bot: verify_secret_key: invalid secret key (license id: %v)", id)
+	}
+
+	return nil
+}
+
+func (m *manager) Redirect(ctx context.Context, rawMsg livechat.Push) (err error) {
+	metrics.PushesReceived.WithLabelValues(rawMsg.GetAction(), strconv.Itoa(int(rawMsg.GetLicenseID()))).Inc()
+	defer func() {
+		if err != nil {
+			metrics.RedirectErrors.WithLabelValues(rawMsg.GetAction()).Inc()
+		}
+	}()
+
 	app, err := m.apps.Find(rawMsg.GetLicenseID())
 	if err != nil {
 		return fmt.Errorf("bot: redirect_action: %w", err)
 	}
 
-	ctx = auth.WithOAuth(ctx, m.authToken)
-	logEntry := log.WithFields(log.Fields{
-		"license_id": rawMsg.GetLicenseID(),
-		"action":     rawMsg.GetAction(),
-	})
-
-	switch msg := rawMsg.(type) {
-	case *livechat.PushIncomingMessage:
-		logEntry.Debug("Received *PushIncomingMessage")
-		return app.IncomingEvent(ctx, msg)
-	case *livechat.PushIncomingChat:
-		logEntry.Debug("Received *PushIncomingChat")
-		return app.TransferChat(ctx, msg)
-	case *livechat.PushUserAddedToChat:
-		logEntry.WithField("raw_message", rawMsg).Debug("Received *PushUserAddedToChat")
-		return app.UserAddedToChat(ctx, msg)
-	default:
-		logEntry.Warn("Received webhook with unknown message")
-		return errors.New("bot: received webhook with unknown message")
+	ctx, err = app.WithOAuth(ctx)
+	if err != nil {
+		return fmt.Errorf("bot: redirect_action: %w", err)
+	}
+
+	if chats.Changes(rawMsg) {
+		defer m.persist(app)
 	}
+	return app.chats.Handle(ctx, rawMsg)
+}
+
+func (m *manager) Licenses() []*bot.LicenseInfo {
+	licenses := []*bot.LicenseInfo{}
+	for _, app := range m.apps.List() {
+		licenses = append(licenses, app.Info())
+	}
+	return licenses
+}
+
+func (m *manager) Authorized() []livechat.LicenseID {
+	return m.tokens.Licenses()
+}
+
+func (m *manager) License(id livechat.LicenseID) (*bot.LicenseInfo, error) {
+	app, err := m.apps.Find(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w (license id: %v)", bot.ErrLicenseNotFound, id)
+	}
+	return app.Info(), nil
+}
+
+func (m *manager) ReleaseChat(ctx context.Context, id livechat.LicenseID, chatID livechat.ChatID) error {
+	app, err := m.apps.Find(id)
+	if err != nil {
+		return fmt.Errorf("%w (license id: %v)", bot.ErrLicenseNotFound, id)
+	}
+
+	ctx, err = app.WithOAuth(ctx)
+	if err != nil {
+		return fmt.Errorf("bot: release_chat: %w", err)
+	}
+
+	defer m.persist(app)
+	return app.chats.ReleaseChat(ctx, chatID)
 }
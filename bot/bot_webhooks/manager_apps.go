@@ -56,3 +56,10 @@ func (a *apps) Find(licenseID livechat.LicenseID) (*app, error) {
 	}
 	return nil, fmt.Errorf("bot: app (license id: %v) is not installed", licenseID)
 }
+
+func (a *apps) List() []*app {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]*app{}, a.apps...)
+}
@@ -0,0 +1,112 @@
+package bot_webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// Restore registers every app saved in store. Restored apps handle
+// webhooks as soon as their licenses get authorized again, apps with
+// saved token are reinstalled with it right away.
+func (m *manager) Restore(ctx context.Context, client livechat.Client, credentials *auth.AuthorizeCredentials) error {
+	licenses, err := m.store.Load()
+	if err != nil {
+		return fmt.Errorf("bot: restore: %w", err)
+	}
+
+	for _, license := range licenses {
+		app := restoreApp(m.lcHTTP, m.sender, m.router, m.capacity, license, m.localURL)
+		if err := m.apps.Register(app); err != nil {
+			log.WithField("license_id", license.ID).WithError(err).Warn("Cannot restore app")
+			continue
+		}
+
+		restored := license.Token != nil
+		if restored {
+			m.tokens.Restore(license.ID, client, credentials, auth.Token{
+				AccessToken:  license.Token.AccessToken,
+				RefreshToken: license.Token.RefreshToken,
+				Expiry:       license.Token.Expiry,
+			})
+		}
+
+		go m.awaitAuthorization(ctx, app, restored)
+		log.WithField("license_id", license.ID).WithField("agents_num", app.chats.Agents().Len()).Debug("App restored")
+	}
+
+	return nil
+}
+
+// awaitAuthorization reinstalls app once its license is authorized. If the
+// saved token does not work (e.g. it has been revoked), app waits for
+// the license to be authorized again.
+func (m *manager) awaitAuthorization(ctx context.Context, app *app, restored bool) {
+	tokens, err := m.tokens.Wait(ctx, app.licenseID)
+	if err != nil {
+		return
+	}
+
+	if err := m.reinstall(ctx, app, tokens); err != nil {
+		log.WithField("license_id", app.licenseID).WithError(err).Error("Cannot reinstall restored app")
+		if restored {
+			m.tokens.Delete(app.licenseID)
+			m.awaitAuthorization(ctx, app, false)
+		}
+		return
+	}
+
+	log.WithField("license_id", app.licenseID).Debug("Restored app has been authorized")
+}
+
+func (m *manager) reinstall(ctx context.Context, app *app, tokens *auth.TokenSource) error {
+	reinstallCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	reinstallCtx, err := tokens.WithOAuth(reinstallCtx)
+	if err != nil {
+		return err
+	}
+	if err := app.Reinstall(reinstallCtx); err != nil {
+		return err
+	}
+
+	m.setTokens(app, tokens)
+	m.persist(app)
+	return nil
+}
+
+// setTokens authorizes app with tokens, app is saved again every time
+// the token changes.
+func (m *manager) setTokens(app *app, tokens *auth.TokenSource) {
+	app.SetTokens(tokens)
+	tokens.OnRefresh(func() { m.persist(app) })
+}
+
+// persist saves state of app unless it has been unregistered, e.g. by
+// UninstallApp while a push of its license was still being handled.
+func (m *manager) persist(app *app) {
+	app.muPersist.Lock()
+	defer app.muPersist.Unlock()
+
+	if registered, err := m.apps.Find(app.licenseID); err != nil || registered != app {
+		return
+	}
+	if err := m.store.Save(app.Snapshot()); err != nil {
+		log.WithField("license_id", app.licenseID).WithError(err).Error("Cannot save app state")
+	}
+}
+
+// forget removes state of unregistered app from store.
+func (m *manager) forget(app *app) {
+	app.muPersist.Lock()
+	defer app.muPersist.Unlock()
+
+	if err := m.store.Delete(app.licenseID); err != nil {
+		log.WithField("license_id", app.licenseID).WithError(err).Error("Cannot remove app state")
+	}
+}
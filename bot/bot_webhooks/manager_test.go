@@ -4,16 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/chats"
+	"github.com/livechat/onboarding/bot/flow"
+	"github.com/livechat/onboarding/bot/handoff"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/bot/store"
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/auth"
 	lcMocks "github.com/livechat/onboarding/livechat/mocks"
+	"github.com/livechat/onboarding/livechat/web"
 	"github.com/livechat/onboarding/livechat/web/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -40,20 +50,34 @@ func Test_Manager_Authorize(t *testing.T) {
 
 	httpClient := new(lcMocks.Client)
 	httpClient.On("Do", mock.Anything).Return(&http.Response{
-		Body:       io.NopCloser(strings.NewReader(`{"access_token":"abcd"}`)),
+		Body:       io.NopCloser(strings.NewReader(`{"access_token":"abcd","license_id":23456}`)),
 		StatusCode: http.StatusOK,
 	}, nil).Once()
 	httpClient.On("Do", mock.Anything).Return(&http.Response{
-		Body:       io.NopCloser(strings.NewReader(`{"access_token":"abcd"}`)),
+		Body:       io.NopCloser(strings.NewReader(`{"access_token":"abcd","license_id":23456}`)),
 		StatusCode: http.StatusOK,
 	}, nil).Once()
 
-	mng := New(lcHTTP, "", "")
+	mng := New(lcHTTP, helperCreateSender(t, lcHTTP), nil, nil, store.NewMemory(), "")
 	assert.NoError(t, mng.Authorize(ctx, httpClient, &auth.AuthorizeCredentials{}))
 	assert.NoError(t, mng.Authorize(ctx, httpClient, &auth.AuthorizeCredentials{}))
 	httpClient.AssertNumberOfCalls(t, "Do", 2)
 }
 
+func Test_Manager_Authorize_MissingLicense(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	httpClient := new(lcMocks.Client)
+	httpClient.On("Do", mock.Anything).Return(&http.Response{
+		Body:       io.NopCloser(strings.NewReader(`{"access_token":"abcd"}`)),
+		StatusCode: http.StatusOK,
+	}, nil).Once()
+
+	mng := New(lcHTTP, helperCreateSender(t, lcHTTP), nil, nil, store.NewMemory(), "")
+	assert.Error(t, mng.Authorize(ctx, httpClient, &auth.AuthorizeCredentials{}))
+}
+
 func Test_Manager_Install(t *testing.T) {
 	ctx := context.Background()
 	lcHTTP := new(mocks.LivechatRequests)
@@ -67,12 +91,9 @@ func Test_Manager_Install(t *testing.T) {
 
 	app := manager.apps.apps[0]
 	assert.NotNil(t, app)
-	assert.Equal(t, 1, app.agents.Len())
+	assert.Equal(t, 1, app.chats.Agents().Len())
 
-	a, unlock := app.agents.Get()
-	defer unlock()
-
-	assert.Equal(t, validBotID, a[0].ID)
+	assert.Equal(t, validBotID, app.chats.Agents().Snapshot()[0].ID)
 }
 
 func Test_Manager_Uninstall_InvalidLicenseID(t *testing.T) {
@@ -99,6 +120,66 @@ func Test_Manager_Uninstall_ValidLicenseID(t *testing.T) {
 	assert.NoError(t, manager.UninstallApp(ctx, validLicenseID))
 }
 
+func Test_Manager_Uninstall_CleanupFailed(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("DisableLicenseWebhook", matchCtx, mock.Anything).Once().Return(nil, web.ErrNotFound)
+	lcHTTP.On("UnregisterWebhook", matchCtx, mock.Anything).Times(webhooksLen).Return(&livechat.UnregisterWebhookResponse{}, nil)
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	manager, err := helperCreateManager(t, ctx, lcHTTP)
+	assert.NoError(t, err)
+
+	err = manager.UninstallApp(ctx, validLicenseID)
+	assert.True(t, errors.Is(err, web.ErrNotFound))
+	lcHTTP.AssertNumberOfCalls(t, "UnregisterWebhook", webhooksLen)
+	lcHTTP.AssertNumberOfCalls(t, "SetRoutingStatus", 2)
+	assert.Empty(t, manager.Licenses())
+}
+
+func Test_Manager_Uninstall_ForgetsState(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("DisableLicenseWebhook", matchCtx, mock.Anything).Once().Return(&livechat.DisableLicenseWebhookResponse{}, nil)
+	lcHTTP.On("UnregisterWebhook", matchCtx, mock.Anything).Times(webhooksLen).Return(&livechat.UnregisterWebhookResponse{}, nil)
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	manager, err := helperCreateManager(t, ctx, lcHTTP)
+	assert.NoError(t, err)
+
+	app := manager.apps.apps[0]
+	assert.NoError(t, manager.UninstallApp(ctx, validLicenseID))
+
+	// push of uninstalled license which has still been handled
+	manager.persist(app)
+
+	licenses, err := manager.store.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, licenses)
+}
+
+func Test_Manager_Uninstall_KeepsOtherLicenses(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("DisableLicenseWebhook", matchCtx, mock.Anything).Once().Return(&livechat.DisableLicenseWebhookResponse{}, nil)
+	lcHTTP.On("UnregisterWebhook", matchCtx, mock.Anything).Times(webhooksLen).Return(&livechat.UnregisterWebhookResponse{}, nil)
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	manager, err := helperCreateManager(t, ctx, lcHTTP)
+	assert.NoError(t, err)
+
+	manager.tokens.Set(invalidLicenseID, nil, &auth.AuthorizeCredentials{}, &auth.AuthorizationResponse{AccessToken: "other_token"})
+	assert.NoError(t, manager.UninstallApp(ctx, validLicenseID))
+
+	_, err = manager.tokens.Get(validLicenseID)
+	assert.Error(t, err)
+	_, err = manager.tokens.Get(invalidLicenseID)
+	assert.NoError(t, err)
+}
+
 func Test_Manager_Redirect_IncomingChat(t *testing.T) {
 	ctx := context.Background()
 	lcHTTP := new(mocks.LivechatRequests)
@@ -112,13 +193,35 @@ func Test_Manager_Redirect_IncomingChat(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_Manager_Redirect_IncomingChat_Routing(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+	app, _ := manager.apps.Find(validLicenseID)
+	router, _ := routing.New([]*routing.Rule{
+		{Groups: []int{2}, Skip: true},
+		{Customer: map[string]string{"email": "*@example.com"}, Bots: []int{1}},
+	})
+	helperSetChats(t, app, lcHTTP, router, nil)
+
+	push := helperBuildPushIncomingChat(t, validLicenseID, validChatID)
+	push.Payload.Chat.Access = &livechat.Access{GroupIDs: []int{0, 2}}
+	assert.NoError(t, manager.Redirect(ctx, push))
+	lcHTTP.AssertNotCalled(t, "TransferChat", matchCtx, mock.Anything)
+
+	push = helperBuildPushIncomingChat(t, validLicenseID, validChatID)
+	push.Payload.Chat.Users = []*livechat.User{{ID: "customer_1", Type: livechat.UserTypeCustomer, Email: "john@example.com"}}
+	assert.Error(t, manager.Redirect(ctx, push))
+	lcHTTP.AssertNotCalled(t, "TransferChat", matchCtx, mock.Anything)
+}
+
 func Test_Manager_Redirect_IncomingEvent(t *testing.T) {
 	ctx := context.Background()
 	lcHTTP := new(mocks.LivechatRequests)
 
-	message := helperBuildPushIncomingEvent(t, validLicenseID, validChatID)
-	message.Payload.Event.Text = "Hello world"
-	message.Payload.Event.AuthorID = "custom_author_id"
+	message := helperBuildPushIncomingEvent(t, validLicenseID, validChatID, "Hello world")
+	message.Payload.Event.Meta().AuthorID = "custom_author_id"
 
 	manager, _ := helperCreateManager(t, ctx, lcHTTP)
 	err := manager.Redirect(ctx, message)
@@ -138,9 +241,8 @@ func Test_Manager_Redirect_IncomingEvent_TransferChat(t *testing.T) {
 		{AgentID: livechat.AgentID("agent_1234")},
 	}, nil)
 
-	message := helperBuildPushIncomingEvent(t, validLicenseID, validChatID)
-	message.Payload.Event.Text = "Wróć do człowieka"
-	message.Payload.Event.AuthorID = "custom_author_id"
+	message := helperBuildPushIncomingEvent(t, validLicenseID, validChatID, "Wróć do człowieka")
+	message.Payload.Event.Meta().AuthorID = "custom_author_id"
 
 	manager, _ := helperCreateManager(t, ctx, lcHTTP)
 	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
@@ -148,6 +250,27 @@ func Test_Manager_Redirect_IncomingEvent_TransferChat(t *testing.T) {
 	lcHTTP.AssertNumberOfCalls(t, "TransferChat", 2)
 }
 
+func Test_Manager_Redirect_IncomingPostback(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("TransferChat", matchCtx, mock.MatchedBy(func(p *livechat.TransferChatRequest) bool {
+		return p.ID == validChatID
+	})).Twice().Return(&livechat.TransferChatResponse{}, nil)
+	lcHTTP.On("ListAgentsForTransfer", matchCtx, mock.Anything).Once().Return([]*livechat.ListAgentsForTransferResponse{
+		{AgentID: livechat.AgentID("agent_1234")},
+	}, nil)
+
+	postback := &livechat.PushIncomingRichMessagePostback{Action: "incoming_rich_message_postback", LicenseID: validLicenseID}
+	postback.Payload.ChatID = validChatID
+	postback.Payload.Postback.ID = "transfer_to_human"
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
+	assert.NoError(t, manager.Redirect(ctx, postback))
+	lcHTTP.AssertNumberOfCalls(t, "TransferChat", 2)
+}
+
 func Test_Manager_UserAddedToChat(t *testing.T) {
 	ctx := context.Background()
 	lcHTTP := new(mocks.LivechatRequests)
@@ -156,13 +279,328 @@ func Test_Manager_UserAddedToChat(t *testing.T) {
 
 	manager, _ := helperCreateManager(t, ctx, lcHTTP)
 	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
-	assert.Equal(t, 1, manager.apps.apps[0].agents.Len())
+	assert.Equal(t, 1, manager.apps.apps[0].chats.Agents().Len())
 
 	assert.NoError(t, manager.Redirect(ctx, helperBuildPushUserAddedToChat(t, validLicenseID, validChatID)))
-	_, err := manager.apps.apps[0].agents.FindByChat(validChatID)
+	_, err := manager.apps.apps[0].chats.Agents().FindByChat(validChatID)
 	assert.Error(t, err)
 }
 
+func Test_Manager_Capacity(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("TransferChat", matchCtx, mock.Anything).Twice().Return(&livechat.TransferChatResponse{}, nil)
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+	app := manager.apps.apps[0]
+	helperSetChats(t, app, lcHTTP, nil, &agents.Capacity{MaxChats: 1, MaxBots: 2})
+	first, _ := app.chats.Agents().FindByID(validBotID)
+	first.MaxChats = 1
+
+	// +grow
+	lcHTTP.On("CreateBot", matchCtx, mock.Anything).Once().Return(&livechat.CreateBotResponse{ID: "bot_2"}, nil)
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Once().Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, "chat_1")))
+	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, "chat_2")))
+	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, "chat_3")))
+
+	second, err := app.chats.Agents().FindByChat("chat_2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, livechat.AgentID("bot_2"), second.ID)
+	}
+	_, err = app.chats.Agents().FindByChat("chat_3")
+	assert.Error(t, err)
+	lcHTTP.AssertNumberOfCalls(t, "TransferChat", 2)
+	lcHTTP.AssertNumberOfCalls(t, "CreateBot", 2)
+}
+
+func Test_Manager_ChatLifecycle(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("TransferChat", matchCtx, mock.Anything).Return(&livechat.TransferChatResponse{}, nil)
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+	chats := func() []livechat.ChatID {
+		license, _ := manager.License(validLicenseID)
+		return license.Bots[0].Chats
+	}
+
+	t.Run("chat deactivated", func(t *testing.T) {
+		assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
+		assert.Equal(t, []livechat.ChatID{validChatID}, chats())
+
+		deactivated := &livechat.PushChatDeactivated{Action: "chat_deactivated", LicenseID: validLicenseID}
+		deactivated.Payload.ChatID = validChatID
+		assert.NoError(t, manager.Redirect(ctx, deactivated))
+		assert.Empty(t, chats())
+
+		licenses, err := manager.store.Load()
+		assert.NoError(t, err)
+		assert.Empty(t, licenses[0].Bots[0].Chats)
+	})
+
+	t.Run("user removed from chat", func(t *testing.T) {
+		assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
+
+		removed := &livechat.PushUserRemovedFromChat{Action: "user_removed_from_chat", LicenseID: validLicenseID}
+		removed.Payload.ChatID = validChatID
+		removed.Payload.UserID = "human_agent"
+		assert.NoError(t, manager.Redirect(ctx, removed))
+		assert.Equal(t, []livechat.ChatID{validChatID}, chats())
+
+		removed.Payload.UserID = string(validBotID)
+		assert.NoError(t, manager.Redirect(ctx, removed))
+		assert.Empty(t, chats())
+	})
+
+	t.Run("chat transferred", func(t *testing.T) {
+		assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
+
+		transferred := &livechat.PushChatTransferred{Action: "chat_transferred", LicenseID: validLicenseID}
+		transferred.Payload.ChatID = validChatID
+		transferred.Payload.TransferredTo.AgentIDs = []livechat.AgentID{validBotID}
+		assert.NoError(t, manager.Redirect(ctx, transferred))
+		assert.Equal(t, []livechat.ChatID{validChatID}, chats())
+
+		transferred.Payload.TransferredTo.AgentIDs = nil
+		transferred.Payload.TransferredTo.GroupIDs = []int{1}
+		assert.NoError(t, manager.Redirect(ctx, transferred))
+		assert.Empty(t, chats())
+	})
+
+	t.Run("taken over chat", func(t *testing.T) {
+		assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
+		assert.NoError(t, manager.Redirect(ctx, helperBuildPushUserAddedToChat(t, validLicenseID, validChatID)))
+
+		deactivated := &livechat.PushChatDeactivated{Action: "chat_deactivated", LicenseID: validLicenseID}
+		deactivated.Payload.ChatID = validChatID
+		assert.NoError(t, manager.Redirect(ctx, deactivated))
+
+		agent, _ := manager.apps.apps[0].chats.Agents().FindByID(validBotID)
+		assert.False(t, agent.ForgetChat(validChatID))
+	})
+}
+
+func Test_Manager_ConcurrentPushes(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("TransferChat", matchCtx, mock.Anything).Return(&livechat.TransferChatResponse{}, nil)
+	lcHTTP.On("SendEvent", matchCtx, mock.Anything).Return(&livechat.SendEventResponse{}, nil)
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			for j := 0; j < 20; j++ {
+				chatID := livechat.ChatID(fmt.Sprintf("chat_%d_%d", worker, j))
+				assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, chatID)))
+				assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingEvent(t, validLicenseID, chatID, "Hello")))
+
+				if j%2 == 0 {
+					deactivated := &livechat.PushChatDeactivated{Action: "chat_deactivated", LicenseID: validLicenseID}
+					deactivated.Payload.ChatID = chatID
+					assert.NoError(t, manager.Redirect(ctx, deactivated))
+				}
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			manager.Licenses()
+			manager.store.Load()
+		}
+	}()
+	wg.Wait()
+
+	license, err := manager.License(validLicenseID)
+	assert.NoError(t, err)
+	assert.Len(t, license.Bots[0].Chats, 8*10)
+	lcHTTP.AssertNumberOfCalls(t, "SendEvent", 8*20)
+}
+
+func Test_Manager_VerifySecretKey(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+	secretKey := manager.apps.apps[0].secretKey
+
+	assert.NotEmpty(t, secretKey)
+	lcHTTP.AssertCalled(t, "RegisterWebhook", matchCtx, mock.MatchedBy(func(p *livechat.RegisterWebhookRequest) bool {
+		return p.SecretKey == secretKey
+	}))
+
+	assert.NoError(t, manager.VerifySecretKey(validLicenseID, secretKey))
+	assert.Error(t, manager.VerifySecretKey(validLicenseID, "random secret key"))
+	assert.Error(t, manager.VerifySecretKey(invalidLicenseID, secretKey))
+}
+
+func Test_Manager_PersistsState(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("TransferChat", matchCtx, mock.Anything).Once().Return(&livechat.TransferChatResponse{}, nil)
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
+
+	licenses, err := manager.store.Load()
+	assert.NoError(t, err)
+	assert.Len(t, licenses, 1)
+	assert.Equal(t, manager.apps.apps[0].secretKey, licenses[0].SecretKey)
+	assert.Equal(t, validBotID, licenses[0].Bots[0].ID)
+	assert.Equal(t, []livechat.ChatID{validChatID}, licenses[0].Bots[0].Chats)
+	if assert.NotNil(t, licenses[0].Token) {
+		assert.Equal(t, oauthToken, licenses[0].Token.AccessToken)
+	}
+}
+
+func Test_Manager_Licenses(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("TransferChat", matchCtx, mock.Anything).Once().Return(&livechat.TransferChatResponse{}, nil)
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
+
+	licenses := manager.Licenses()
+	if assert.Len(t, licenses, 1) {
+		assert.Equal(t, validLicenseID, licenses[0].ID)
+		assert.Len(t, licenses[0].Webhooks, webhooksLen)
+		assert.Equal(t, []livechat.ChatID{validChatID}, licenses[0].Bots[0].Chats)
+	}
+
+	_, err := manager.License(invalidLicenseID)
+	assert.True(t, errors.Is(err, bot.ErrLicenseNotFound))
+}
+
+func Test_Manager_ReleaseChat(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	lcHTTP.On("TransferChat", matchCtx, mock.Anything).Once().Return(&livechat.TransferChatResponse{}, nil)
+	lcHTTP.On("RemoveUserFromChat", matchCtx, mock.MatchedBy(func(p *livechat.RemoveUserFromChatRequest) bool {
+		return p.ChatID == validChatID && p.UserID == validBotID
+	})).Once().Return(&livechat.RemoveUserFromChatResponse{}, nil)
+
+	manager, _ := helperCreateManager(t, ctx, lcHTTP)
+	assert.NoError(t, manager.Redirect(ctx, helperBuildPushIncomingChat(t, validLicenseID, validChatID)))
+
+	assert.NoError(t, manager.ReleaseChat(ctx, validLicenseID, validChatID))
+	_, err := manager.apps.apps[0].chats.Agents().FindByChat(validChatID)
+	assert.Error(t, err)
+
+	licenses, _ := manager.store.Load()
+	assert.Empty(t, licenses[0].Bots[0].Chats)
+
+	err = manager.ReleaseChat(ctx, validLicenseID, validChatID)
+	assert.True(t, errors.Is(err, bot.ErrChatNotFound))
+}
+
+func Test_Manager_Restore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	// +reinstall restored app
+	reinstalled := make(chan bool, 1)
+	lcHTTP.On("UnregisterWebhook", matchCtx, mock.MatchedBy(func(p *livechat.UnregisterWebhookRequest) bool {
+		return p.ID == "webhook_1"
+	})).Once().Return(nil, errors.New("webhook not found"))
+	lcHTTP.On("RegisterWebhook", matchCtx, mock.MatchedBy(func(p *livechat.RegisterWebhookRequest) bool {
+		return p.SecretKey == "restored_secret"
+	})).Times(webhooksLen).Return(&livechat.RegisterWebhookResponse{}, nil)
+	lcHTTP.On("EnableLicenseWebhook", matchCtx, mock.Anything).Once().Return(&livechat.EnableLicenseWebhookResponse{}, nil)
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Once().Run(func(mock.Arguments) {
+		reinstalled <- true
+	}).Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	botStore := store.NewMemory()
+	botStore.Save(&store.License{
+		ID:        validLicenseID,
+		SecretKey: "restored_secret",
+		Webhooks:  map[string]string{"incoming_chat": "webhook_1"},
+		Bots:      []*store.Bot{{ID: validBotID, MaxChats: 2, Chats: []livechat.ChatID{validChatID}}},
+	})
+
+	mng := New(lcHTTP, helperCreateSender(t, lcHTTP), nil, nil, botStore, "http://localhost:8081")
+	assert.NoError(t, mng.Restore(ctx, nil, &auth.AuthorizeCredentials{}))
+	assert.NoError(t, mng.VerifySecretKey(validLicenseID, "restored_secret"))
+
+	manager := mng.(*manager)
+	agent, err := manager.apps.apps[0].chats.Agents().FindByChat(validChatID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, validBotID, agent.ID)
+		assert.Equal(t, 2, agent.MaxChats)
+	}
+
+	manager.tokens.Set(validLicenseID, nil, &auth.AuthorizeCredentials{}, &auth.AuthorizationResponse{AccessToken: oauthToken})
+	for {
+		// admin API reads the app while it is being reinstalled
+		manager.Licenses()
+
+		select {
+		case <-reinstalled:
+			return
+		case <-ctx.Done():
+			t.Fatalf("restored app has not been reinstalled")
+		default:
+		}
+	}
+}
+
+func Test_Manager_Restore_SavedToken(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	// +reinstall restored app
+	reinstalled := make(chan bool, 1)
+	lcHTTP.On("RegisterWebhook", matchCtx, mock.Anything).Times(webhooksLen).Return(&livechat.RegisterWebhookResponse{}, nil)
+	lcHTTP.On("EnableLicenseWebhook", matchCtx, mock.Anything).Once().Return(&livechat.EnableLicenseWebhookResponse{}, nil)
+	lcHTTP.On("SetRoutingStatus", matchCtx, mock.Anything).Once().Run(func(mock.Arguments) {
+		reinstalled <- true
+	}).Return(&livechat.SetRoutingStatusResponse{}, nil)
+
+	botStore := store.NewMemory()
+	botStore.Save(&store.License{
+		ID:        validLicenseID,
+		SecretKey: "restored_secret",
+		Bots:      []*store.Bot{{ID: validBotID}},
+		Token:     &store.Token{AccessToken: oauthToken, RefreshToken: "refresh_token", Expiry: time.Now().Add(time.Hour)},
+	})
+
+	mng := New(lcHTTP, helperCreateSender(t, lcHTTP), nil, nil, botStore, "http://localhost:8081")
+	assert.NoError(t, mng.Restore(ctx, nil, &auth.AuthorizeCredentials{}))
+
+	select {
+	case <-reinstalled:
+	case <-ctx.Done():
+		t.Fatalf("restored app has not been reinstalled with saved token")
+	}
+	assert.Equal(t, []livechat.LicenseID{validLicenseID}, mng.Authorized())
+}
+
+// helperSetChats replaces chat handling of app, bots of app are kept.
+func helperSetChats(t *testing.T, app *app, lcHTTP *mocks.LivechatRequests, router *routing.Router, capacity *agents.Capacity) {
+	t.Helper()
+	bots := app.chats.Agents()
+	app.chats = chats.New(lcHTTP, helperCreateSender(t, lcHTTP), router, capacity, app.licenseID)
+	app.chats.SetAgents(bots)
+}
+
 func helperCreateManager(t *testing.T, ctx context.Context, lcHTTP *mocks.LivechatRequests) (*manager, error) {
 	t.Helper()
 	// +install bots
@@ -183,9 +621,9 @@ func helperCreateManager(t *testing.T, ctx context.Context, lcHTTP *mocks.Livech
 		}
 	}()
 
-	mng := New(lcHTTP, "http://localhost:8081", "author_id")
+	mng := New(lcHTTP, helperCreateSender(t, lcHTTP), nil, nil, store.NewMemory(), "http://localhost:8081")
 	go func() {
-		byteBody, err := json.Marshal(map[string]string{"access_token": oauthToken})
+		byteBody, err := json.Marshal(map[string]interface{}{"access_token": oauthToken, "license_id": validLicenseID})
 		if err != nil {
 			return
 		}
@@ -214,50 +652,38 @@ func helperCreateManager(t *testing.T, ctx context.Context, lcHTTP *mocks.Livech
 	return rawManager, nil
 }
 
-func helperBuildPushIncomingChat(t *testing.T, licenseID livechat.LicenseID, chatID livechat.ChatID) *livechat.PushIncomingChat {
+func helperCreateSender(t *testing.T, lcHTTP *mocks.LivechatRequests) bot.Sender {
 	t.Helper()
-	return &livechat.PushIncomingChat{
-		Action:    "incoming_chat",
-		LicenseID: licenseID,
-		Payload: struct {
-			Chat struct {
-				ID livechat.ChatID "json:\"id\""
-			} "json:\"chat\""
-		}{
-			Chat: struct {
-				ID livechat.ChatID "json:\"id\""
-			}{
-				ID: chatID,
-			},
-		},
+
+	conversation, err := flow.New(flow.Default())
+	if err != nil {
+		t.Fatalf("cannot create flow: %s", err)
+	}
+	transfer, err := handoff.New(lcHTTP, &handoff.Config{})
+	if err != nil {
+		t.Fatalf("cannot create handoff: %s", err)
 	}
+	return bot.NewSender(lcHTTP, "author_id", conversation, transfer)
 }
 
-func helperBuildPushIncomingEvent(t *testing.T, licenseID livechat.LicenseID, chatID livechat.ChatID) *livechat.PushIncomingMessage {
+func helperBuildPushIncomingChat(t *testing.T, licenseID livechat.LicenseID, chatID livechat.ChatID) *livechat.PushIncomingChat {
 	t.Helper()
 
-	return &livechat.PushIncomingMessage{
-		Action:    "incoming_message",
-		LicenseID: licenseID,
-		Payload: struct {
-			ChatID   livechat.ChatID "json:\"chat_id\""
-			ThreadID string          "json:\"thread_id,omitempty\""
-			Event    struct {
-				Type     string "json:\"type\""
-				Text     string "json:\"text\""
-				AuthorID string "json:\"author_id\""
-			} "json:\"event\""
-		}{
-			ChatID: chatID,
-			Event: struct {
-				Type     string "json:\"type\""
-				Text     string "json:\"text\""
-				AuthorID string "json:\"author_id\""
-			}{
-				Type: "message",
-			},
-		},
+	push := &livechat.PushIncomingChat{Action: "incoming_chat", LicenseID: licenseID}
+	push.Payload.Chat.ID = chatID
+	return push
+}
+
+func helperBuildPushIncomingEvent(t *testing.T, licenseID livechat.LicenseID, chatID livechat.ChatID, text string) *livechat.PushIncomingMessage {
+	t.Helper()
+
+	msg := &livechat.PushIncomingMessage{Action: "incoming_event", LicenseID: licenseID}
+	msg.Payload.ChatID = chatID
+	msg.Payload.Event.ChatEvent = &livechat.MessageEvent{
+		EventMeta: livechat.EventMeta{Type: livechat.EventTypeMessage},
+		Text:      text,
 	}
+	return msg
 }
 
 func helperBuildPushUserAddedToChat(t *testing.T, licenseID livechat.LicenseID, chatID livechat.ChatID) *livechat.PushUserAddedToChat {
@@ -0,0 +1,290 @@
+// Package chats handles pushes about chats of a single license. It does
+// not depend on the transport, so it is shared by webhook and RTM bots.
+package chats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/auth"
+	"github.com/livechat/onboarding/livechat/web"
+	"github.com/livechat/onboarding/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrUnknownPush = errors.New("bot: received push with unknown message")
+
+// Handler assigns chats of license to its bots and passes messages
+// of assigned chats to sender.
+type Handler struct {
+	lcHTTP    web.LivechatRequests
+	sender    bot.Sender
+	router    *routing.Router
+	capacity  *agents.Capacity
+	licenseID livechat.LicenseID
+
+	muAgents sync.RWMutex
+	agents   agents.Agents
+
+	// muGrow makes sure only one bot is added at once.
+	muGrow sync.Mutex
+}
+
+// New creates handler with no bots. Every bot may take every chat if router
+// is nil, bots take any number of chats if capacity is nil.
+func New(lcHTTP web.LivechatRequests, sender bot.Sender, router *routing.Router, capacity *agents.Capacity, licenseID livechat.LicenseID) *Handler {
+	return &Handler{
+		lcHTTP:    lcHTTP,
+		sender:    sender,
+		router:    router,
+		capacity:  capacity,
+		licenseID: licenseID,
+		agents:    agents.NewCollection(),
+	}
+}
+
+// Agents returns bots of license.
+func (h *Handler) Agents() agents.Agents {
+	h.muAgents.RLock()
+	defer h.muAgents.RUnlock()
+
+	return h.agents
+}
+
+// SetAgents replaces bots of license, e.g. once they have been initialized.
+func (h *Handler) SetAgents(bots agents.Agents) {
+	h.muAgents.Lock()
+	defer h.muAgents.Unlock()
+
+	h.agents = bots
+}
+
+// Changes reports whether push may change chats assigned to bots.
+func Changes(push livechat.Push) bool {
+	switch push.(type) {
+	case *livechat.PushIncomingMessage, *livechat.PushIncomingRichMessagePostback:
+		return false
+	default:
+		return true
+	}
+}
+
+// Handle reacts to push, ctx has to be authorized with token of license.
+func (h *Handler) Handle(ctx context.Context, rawMsg livechat.Push) error {
+	logEntry := log.WithFields(log.Fields{
+		"license_id": rawMsg.GetLicenseID(),
+		"action":     rawMsg.GetAction(),
+	})
+
+	switch msg := rawMsg.(type) {
+	case *livechat.PushIncomingMessage:
+		logEntry.Debug("Received *PushIncomingMessage")
+		return h.incomingEvent(ctx, msg)
+	case *livechat.PushIncomingChat:
+		logEntry.Debug("Received *PushIncomingChat")
+		return h.transferChat(ctx, msg)
+	case *livechat.PushIncomingRichMessagePostback:
+		logEntry.Debug("Received *PushIncomingRichMessagePostback")
+		return h.incomingPostback(ctx, msg)
+	case *livechat.PushUserAddedToChat:
+		logEntry.WithField("raw_message", rawMsg).Debug("Received *PushUserAddedToChat")
+		return h.userAddedToChat(ctx, msg)
+	case *livechat.PushChatDeactivated:
+		logEntry.Debug("Received *PushChatDeactivated")
+		return h.chatDeactivated(ctx, msg)
+	case *livechat.PushUserRemovedFromChat:
+		logEntry.Debug("Received *PushUserRemovedFromChat")
+		return h.userRemovedFromChat(ctx, msg)
+	case *livechat.PushChatTransferred:
+		logEntry.Debug("Received *PushChatTransferred")
+		return h.chatTransferred(ctx, msg)
+	default:
+		logEntry.Warn("Received push with unknown message")
+		return ErrUnknownPush
+	}
+}
+
+func (h *Handler) transferChat(ctx context.Context, msg *livechat.PushIncomingChat) error {
+	decision := h.router.Route(&msg.Payload.Chat)
+	if decision.Skip {
+		log.WithFields(log.Fields{
+			"license_id": h.licenseID,
+			"chat_id":    msg.Payload.Chat.ID,
+			"rule":       decision.Rule,
+		}).Debug("Chat is left for humans")
+		return nil
+	}
+
+	agent, err := h.findFree(ctx, msg.Payload.Chat.ID, decision)
+	if errors.Is(err, agents.ErrAtCapacity) {
+		metrics.ChatsAtCapacity.WithLabelValues(strconv.Itoa(int(h.licenseID))).Inc()
+		log.WithFields(log.Fields{
+			"license_id": h.licenseID,
+			"chat_id":    msg.Payload.Chat.ID,
+		}).Info("Chat is left for humans, every bot is at capacity")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("bot: transfer_chat action: %w", err)
+	}
+
+	if _, err = h.lcHTTP.TransferChat(ctx, buildTransferChatMessage(msg.Payload.Chat.ID, agent.ID)); !isTransferChatErrorOk(err) {
+		return fmt.Errorf("bot: transfer_chat action: %w", err)
+	}
+
+	return agent.RegisterChat(msg.Payload.Chat.ID)
+}
+
+// findFree picks the least loaded bot allowed to take the chat. Another
+// bot is added if every one of them is at capacity and routing allows
+// the chat to be taken by the new bot.
+func (h *Handler) findFree(ctx context.Context, chatID livechat.ChatID, decision *routing.Decision) (*agents.Agent, error) {
+	bots := h.Agents()
+
+	agent, err := bots.FindFree(chatID, decision.Allows)
+	if !errors.Is(err, agents.ErrAtCapacity) || !decision.Allows(bots.Len()) {
+		return agent, err
+	}
+
+	h.muGrow.Lock()
+	defer h.muGrow.Unlock()
+
+	if agent, err = bots.FindFree(chatID, decision.Allows); !errors.Is(err, agents.ErrAtCapacity) || !decision.Allows(bots.Len()) {
+		return agent, err
+	}
+	return agents.Grow(ctx, h.lcHTTP, bots, h.capacity)
+}
+
+func (h *Handler) incomingEvent(ctx context.Context, msg *livechat.PushIncomingMessage) error {
+	agent, err := h.Agents().FindByChat(msg.Payload.ChatID)
+	if err != nil {
+		return nil
+	}
+
+	return h.sender.Talk(auth.WithAuthorID(ctx, agent.ID), msg.Payload.ChatID, msg)
+}
+
+func (h *Handler) incomingPostback(ctx context.Context, msg *livechat.PushIncomingRichMessagePostback) error {
+	agent, err := h.Agents().FindByChat(msg.Payload.ChatID)
+	if err != nil {
+		return nil
+	}
+
+	return h.sender.Postback(auth.WithAuthorID(ctx, agent.ID), msg.Payload.ChatID, msg)
+}
+
+func (h *Handler) userAddedToChat(ctx context.Context, msg *livechat.PushUserAddedToChat) error {
+	agent, err := h.Agents().FindByChat(msg.Payload.ChatID)
+	if err != nil {
+		return nil
+	}
+
+	if msg.Payload.User.Present && msg.Payload.User.Type == "agent" {
+		h.sender.Forget(msg.Payload.ChatID)
+		return agent.UnregisterChat(msg.Payload.ChatID)
+	}
+
+	return nil
+}
+
+// chatDeactivated forgets the closed chat.
+func (h *Handler) chatDeactivated(ctx context.Context, msg *livechat.PushChatDeactivated) error {
+	h.forgetChat(msg.Payload.ChatID)
+	return nil
+}
+
+// userRemovedFromChat forgets the chat if bot has been removed from it.
+func (h *Handler) userRemovedFromChat(ctx context.Context, msg *livechat.PushUserRemovedFromChat) error {
+	agent, err := h.Agents().FindByID(livechat.AgentID(msg.Payload.UserID))
+	if err != nil {
+		return nil
+	}
+
+	if agent.ForgetChat(msg.Payload.ChatID) {
+		h.sender.Forget(msg.Payload.ChatID)
+	}
+	return nil
+}
+
+// chatTransferred keeps the chat only if it has been transferred to
+// one of bots, otherwise the chat is forgotten.
+func (h *Handler) chatTransferred(ctx context.Context, msg *livechat.PushChatTransferred) error {
+	bots := h.Agents()
+
+	chatID := msg.Payload.ChatID
+	for _, agentID := range msg.Payload.TransferredTo.AgentIDs {
+		target, err := bots.FindByID(agentID)
+		if err != nil {
+			continue
+		}
+
+		if current, err := bots.FindByChat(chatID); err == nil && current == target {
+			return nil
+		}
+		h.forgetChat(chatID)
+		return target.RegisterChat(chatID)
+	}
+
+	h.forgetChat(chatID)
+	return nil
+}
+
+func (h *Handler) forgetChat(chatID livechat.ChatID) {
+	if !h.Agents().ForgetChat(chatID) {
+		return
+	}
+
+	h.sender.Forget(chatID)
+	log.WithField("license_id", h.licenseID).WithField("chat_id", chatID).Debug("Chat forgotten")
+}
+
+// ReleaseChat removes bot from the chat, so it is no longer served by bot.
+func (h *Handler) ReleaseChat(ctx context.Context, chatID livechat.ChatID) error {
+	agent, err := h.Agents().FindByChat(chatID)
+	if err != nil {
+		return fmt.Errorf("%w (chat id: %v)", bot.ErrChatNotFound, chatID)
+	}
+
+	_, err = h.lcHTTP.RemoveUserFromChat(ctx, &livechat.RemoveUserFromChatRequest{
+		ChatID:   chatID,
+		UserID:   agent.ID,
+		UserType: "agent",
+	})
+	if err != nil && !errors.Is(err, web.ErrNotFound) {
+		return fmt.Errorf("bot: release_chat: %w", err)
+	}
+
+	h.sender.Forget(chatID)
+	return agent.UnregisterChat(chatID)
+}
+
+// Bots returns bots of license with chats they serve.
+func (h *Handler) Bots() []*bot.BotInfo {
+	bots := []*bot.BotInfo{}
+	for _, agent := range h.Agents().Snapshot() {
+		bots = append(bots, &bot.BotInfo{ID: agent.ID, Chats: agent.Chats})
+	}
+	return bots
+}
+
+func buildTransferChatMessage(chatID livechat.ChatID, agentID livechat.AgentID) *livechat.TransferChatRequest {
+	return &livechat.TransferChatRequest{
+		ID: chatID,
+		Target: livechat.TransferTarget{
+			Type: livechat.TransferTargetAgent,
+			IDs:  []livechat.AgentID{agentID},
+		},
+		Force: false,
+	}
+}
+
+func isTransferChatErrorOk(err error) bool {
+	return err == nil || errors.Is(err, web.ErrAgentAlreadyInChat)
+}
@@ -0,0 +1,147 @@
+package chats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type senderStub struct {
+	forgotten []livechat.ChatID
+}
+
+func (s *senderStub) Talk(context.Context, livechat.ChatID, *livechat.PushIncomingMessage) error {
+	return nil
+}
+
+func (s *senderStub) Postback(context.Context, livechat.ChatID, *livechat.PushIncomingRichMessagePostback) error {
+	return nil
+}
+
+func (s *senderStub) Forget(chatID livechat.ChatID) {
+	s.forgotten = append(s.forgotten, chatID)
+}
+
+type unknownPush struct{}
+
+func (unknownPush) GetAction() string                { return "unknown" }
+func (unknownPush) GetLicenseID() livechat.LicenseID { return 1 }
+func (unknownPush) GetChatID() livechat.ChatID       { return "" }
+
+func Test_Changes(t *testing.T) {
+	assert.False(t, Changes(&livechat.PushIncomingMessage{}))
+	assert.False(t, Changes(&livechat.PushIncomingRichMessagePostback{}))
+	assert.True(t, Changes(&livechat.PushIncomingChat{}))
+	assert.True(t, Changes(&livechat.PushChatDeactivated{}))
+}
+
+func Test_Handler_ChatTransferred(t *testing.T) {
+	sender := &senderStub{}
+	handler, first, second := helperCreateHandler(t, sender, new(mocks.LivechatRequests), nil, nil)
+
+	assert.NoError(t, first.RegisterChat("chat_1", "chat_2"))
+
+	push := &livechat.PushChatTransferred{}
+	push.Payload.ChatID = "chat_1"
+	push.Payload.TransferredTo.AgentIDs = []livechat.AgentID{"agent_1", second.ID}
+	assert.NoError(t, handler.Handle(context.Background(), push))
+
+	agent, err := handler.Agents().FindByChat("chat_1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, second.ID, agent.ID)
+	}
+	assert.Equal(t, []livechat.ChatID{"chat_1"}, sender.forgotten)
+
+	push = &livechat.PushChatTransferred{}
+	push.Payload.ChatID = "chat_2"
+	push.Payload.TransferredTo.GroupIDs = []int{1}
+	assert.NoError(t, handler.Handle(context.Background(), push))
+
+	_, err = handler.Agents().FindByChat("chat_2")
+	assert.Error(t, err)
+	assert.Equal(t, []livechat.ChatID{"chat_1", "chat_2"}, sender.forgotten)
+}
+
+func Test_Handler_ChatDeactivated(t *testing.T) {
+	sender := &senderStub{}
+	handler, first, _ := helperCreateHandler(t, sender, new(mocks.LivechatRequests), nil, nil)
+
+	assert.NoError(t, first.RegisterChat("chat_1"))
+
+	push := &livechat.PushChatDeactivated{}
+	push.Payload.ChatID = "chat_1"
+	assert.NoError(t, handler.Handle(context.Background(), push))
+	assert.NoError(t, handler.Handle(context.Background(), push))
+
+	assert.Equal(t, 0, first.Load())
+	assert.Equal(t, []livechat.ChatID{"chat_1"}, sender.forgotten)
+}
+
+func Test_Handler_UnknownPush(t *testing.T) {
+	handler, _, _ := helperCreateHandler(t, &senderStub{}, new(mocks.LivechatRequests), nil, nil)
+
+	err := handler.Handle(context.Background(), unknownPush{})
+	assert.True(t, errors.Is(err, ErrUnknownPush))
+}
+
+func Test_Handler_IncomingChat_Routing(t *testing.T) {
+	ctx := context.Background()
+	lcHTTP := new(mocks.LivechatRequests)
+
+	router, err := routing.New([]*routing.Rule{
+		{Groups: []int{1}, Bots: []int{0, 1}},
+		{Groups: []int{2}, Bots: []int{1, 2}},
+	})
+	assert.NoError(t, err)
+	handler, first, second := helperCreateHandler(t, &senderStub{}, lcHTTP, router, &agents.Capacity{MaxChats: 1, MaxBots: 3})
+	first.MaxChats, second.MaxChats = 1, 1
+	assert.NoError(t, first.RegisterChat("chat_1"))
+	assert.NoError(t, second.RegisterChat("chat_2"))
+
+	// bots allowed by the rule are at capacity and it does not allow more bots
+	assert.NoError(t, handler.Handle(ctx, helperBuildPushIncomingChat(t, "chat_3", 1)))
+	lcHTTP.AssertNotCalled(t, "CreateBot", mock.Anything, mock.Anything)
+
+	// +grow
+	lcHTTP.On("CreateBot", mock.Anything, mock.Anything).Once().Return(&livechat.CreateBotResponse{ID: "bot_3"}, nil)
+	lcHTTP.On("SetRoutingStatus", mock.Anything, mock.Anything).Once().Return(&livechat.SetRoutingStatusResponse{}, nil)
+	lcHTTP.On("TransferChat", mock.Anything, mock.MatchedBy(func(p *livechat.TransferChatRequest) bool {
+		return p.ID == "chat_4" && p.Target.IDs[0] == "bot_3"
+	})).Once().Return(&livechat.TransferChatResponse{}, nil)
+
+	assert.NoError(t, handler.Handle(ctx, helperBuildPushIncomingChat(t, "chat_4", 2)))
+	agent, err := handler.Agents().FindByChat("chat_4")
+	if assert.NoError(t, err) {
+		assert.Equal(t, livechat.AgentID("bot_3"), agent.ID)
+	}
+	lcHTTP.AssertExpectations(t)
+}
+
+func helperBuildPushIncomingChat(t *testing.T, chatID livechat.ChatID, groupID int) *livechat.PushIncomingChat {
+	t.Helper()
+
+	push := &livechat.PushIncomingChat{Action: "incoming_chat", LicenseID: 1}
+	push.Payload.Chat.ID = chatID
+	push.Payload.Chat.Access = &livechat.Access{GroupIDs: []int{groupID}}
+	return push
+}
+
+func helperCreateHandler(t *testing.T, sender *senderStub, lcHTTP *mocks.LivechatRequests, router *routing.Router, capacity *agents.Capacity) (*Handler, *agents.Agent, *agents.Agent) {
+	t.Helper()
+
+	first, second := agents.NewAgent("bot_1"), agents.NewAgent("bot_2")
+	bots := agents.NewCollection()
+	bots.Register(first)
+	bots.Register(second)
+
+	handler := New(lcHTTP, sender, router, capacity, 1)
+	handler.SetAgents(bots)
+	return handler, first, second
+}
@@ -0,0 +1,31 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+// Store remembers keys of handled pushes for some time.
+type Store interface {
+	// Add records key. It returns false if key has been already recorded
+	// and not forgotten yet.
+	Add(key string) bool
+	// Remove forgets key, so the push is handled when delivered again.
+	Remove(key string)
+}
+
+// Key identifies push by ID of its event, or by hash of raw payload
+// if push has no event.
+func Key(push livechat.Push, rawBody []byte) string {
+	prefix := fmt.Sprintf("%v:%s", push.GetLicenseID(), push.GetAction())
+
+	if push, ok := push.(livechat.EventPush); ok && push.GetEventID() != "" {
+		return fmt.Sprintf("%s:event:%s", prefix, push.GetEventID())
+	}
+
+	sum := sha256.Sum256(rawBody)
+	return fmt.Sprintf("%s:hash:%s", prefix, hex.EncodeToString(sum[:]))
+}
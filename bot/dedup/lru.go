@@ -0,0 +1,88 @@
+package dedup
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSize   = 10000
+	DefaultWindow = 10 * time.Minute
+)
+
+// LRU keeps at most size keys in memory, each one for the window.
+// The least recently added key is dropped when it is full.
+type LRU struct {
+	size   int
+	window time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	order *list.List
+	keys  map[string]*list.Element
+}
+
+type entry struct {
+	key     string
+	expires time.Time
+}
+
+func NewLRU(size int, window time.Duration) *LRU {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	return &LRU{
+		size:   size,
+		window: window,
+		now:    time.Now,
+		order:  list.New(),
+		keys:   make(map[string]*list.Element, size),
+	}
+}
+
+func (s *LRU) Add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if elem, ok := s.keys[key]; ok {
+		if now.Before(elem.Value.(*entry).expires) {
+			return false
+		}
+		s.remove(elem)
+	}
+
+	for s.order.Len() >= s.size {
+		s.remove(s.order.Back())
+	}
+	s.keys[key] = s.order.PushFront(&entry{key: key, expires: now.Add(s.window)})
+
+	return true
+}
+
+func (s *LRU) Remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.keys[key]; ok {
+		s.remove(elem)
+	}
+}
+
+// Len returns number of remembered keys, including expired ones.
+func (s *LRU) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *LRU) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.keys, elem.Value.(*entry).key)
+}
@@ -0,0 +1,46 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LRU_Window(t *testing.T) {
+	now := time.Now()
+	store := NewLRU(10, time.Minute)
+	store.now = func() time.Time { return now }
+
+	assert.True(t, store.Add("a"))
+	assert.False(t, store.Add("a"))
+
+	now = now.Add(time.Minute)
+	assert.True(t, store.Add("a"))
+
+	store.Remove("a")
+	assert.True(t, store.Add("a"))
+}
+
+func Test_LRU_Evicts(t *testing.T) {
+	store := NewLRU(2, time.Minute)
+
+	assert.True(t, store.Add("a"))
+	assert.True(t, store.Add("b"))
+	assert.True(t, store.Add("c"))
+	assert.Equal(t, 2, store.Len())
+
+	assert.True(t, store.Add("a"))
+	assert.False(t, store.Add("c"))
+}
+
+func Test_Key(t *testing.T) {
+	msg := &livechat.PushIncomingMessage{Action: "incoming_event", LicenseID: 1234}
+	msg.Payload.Event.ChatEvent = &livechat.MessageEvent{EventMeta: livechat.EventMeta{ID: "event_1", Type: livechat.EventTypeMessage}}
+	assert.Equal(t, "1234:incoming_event:event:event_1", Key(msg, []byte("{}")))
+
+	chat := &livechat.PushIncomingChat{Action: "incoming_chat", LicenseID: 1234}
+	assert.Equal(t, Key(chat, []byte(`{"a": 1}`)), Key(chat, []byte(`{"a": 1}`)))
+	assert.NotEqual(t, Key(chat, []byte(`{"a": 1}`)), Key(chat, []byte(`{"a": 2}`)))
+}
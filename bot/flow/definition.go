@@ -0,0 +1,120 @@
+package flow
+
+import "github.com/livechat/onboarding/livechat"
+
+type MatchType string
+type ActionType string
+
+const (
+	MatchExact   MatchType = "exact"
+	MatchRegex   MatchType = "regex"
+	MatchKeyword MatchType = "keyword"
+	// MatchPostback matches postback ID of clicked rich message button.
+	MatchPostback MatchType = "postback"
+	// MatchFile matches content type of file uploaded by customer
+	// with glob patterns, e.g. "image/*" or "*/*" for any file.
+	MatchFile MatchType = "file"
+	// MatchForm matches type of form filled by customer, e.g. "postchat".
+	MatchForm MatchType = "form"
+)
+
+const (
+	// ActionTransfer hands the chat over to a human agent.
+	ActionTransfer ActionType = "transfer"
+)
+
+// Definition describes the whole conversation: states with intents
+// recognized in each of them and the answers bot gives.
+type Definition struct {
+	Initial string            `json:"initial" validate:"required"`
+	States  map[string]*State `json:"states" validate:"required,min=1,dive,required"`
+}
+
+type State struct {
+	Intents []*Intent `json:"intents" validate:"dive,required"`
+	// Fallback is used when none of intents matches the message.
+	Fallback *Response `json:"fallback,omitempty"`
+}
+
+type Intent struct {
+	Name     string   `json:"name"`
+	Match    Match    `json:"match" validate:"required"`
+	Response Response `json:"response"`
+	// Next is the state conversation moves to after the intent is matched.
+	// Conversation stays in the current state if empty.
+	Next string `json:"next,omitempty"`
+}
+
+type Match struct {
+	Type MatchType `json:"type" validate:"required,oneof=exact regex keyword postback file form"`
+	// Values are alternatives, matching any of them matches the intent.
+	Values []string `json:"values" validate:"required,min=1"`
+	// CaseSensitive disables case folding for exact and keyword matches.
+	CaseSensitive bool `json:"case_sensitive,omitempty"`
+}
+
+type Response struct {
+	Text string `json:"text,omitempty"`
+	// Title, Subtitle, Image and Buttons are sent as a card.
+	Title    string     `json:"title,omitempty"`
+	Subtitle string     `json:"subtitle,omitempty"`
+	Image    string     `json:"image,omitempty"`
+	Buttons  []*Button  `json:"buttons,omitempty" validate:"dive,required"`
+	Action   ActionType `json:"action,omitempty" validate:"omitempty,oneof=transfer"`
+}
+
+type Button struct {
+	Text string `json:"text" validate:"required"`
+	// Type is one of "message" (default), "url", "phone" or "webview".
+	Type       livechat.ButtonType `json:"type,omitempty" validate:"omitempty,oneof=message url phone webview"`
+	Value      string              `json:"value,omitempty"`
+	PostbackID string              `json:"postback_id" validate:"required"`
+}
+
+// Default returns conversation which the bot has been shipped with.
+func Default() *Definition {
+	return &Definition{
+		Initial: "start",
+		States: map[string]*State{
+			"start": {
+				Intents: []*Intent{
+					{
+						Name:     "hello",
+						Match:    Match{Type: MatchExact, Values: []string{"Hello"}, CaseSensitive: true},
+						Response: Response{Text: "World!"},
+					},
+					{
+						Name:     "human",
+						Match:    Match{Type: MatchExact, Values: []string{"Wróć do człowieka"}, CaseSensitive: true},
+						Response: Response{Action: ActionTransfer},
+						Next:     "human",
+					},
+					{
+						Name:     "human_button",
+						Match:    Match{Type: MatchPostback, Values: []string{"transfer_to_human"}},
+						Response: Response{Action: ActionTransfer},
+						Next:     "human",
+					},
+					{
+						Name:     "file",
+						Match:    Match{Type: MatchFile, Values: []string{"*/*"}},
+						Response: Response{Text: "Dziękuję za plik, przekażę go człowiekowi.", Action: ActionTransfer},
+						Next:     "human",
+					},
+					{
+						Name:     "form",
+						Match:    Match{Type: MatchForm, Values: []string{"*"}},
+						Response: Response{Text: "Dziękuję, formularz został wysłany."},
+					},
+				},
+				Fallback: &Response{
+					Title:   "Czy chcesz wrócić do człowieka?",
+					Buttons: []*Button{{Text: "Wróć do człowieka", PostbackID: "transfer_to_human"}},
+				},
+			},
+			// Chat waits for a human, so the bot keeps quiet (also when the
+			// clicked button echoes its text as customer's message).
+			"human": {},
+		},
+	}
+}
@@ -0,0 +1,260 @@
+package flow
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+// Flow tracks state of every conversation and picks answers
+// according to the definition.
+type Flow struct {
+	initial string
+	states  map[string]*compiledState
+
+	mu    sync.Mutex
+	chats map[livechat.ChatID]string
+}
+
+type compiledState struct {
+	intents  []*compiledIntent
+	fallback *Response
+}
+
+type compiledIntent struct {
+	*Intent
+	patterns []*regexp.Regexp
+}
+
+// Reply is an answer for the message.
+type Reply struct {
+	// Intent is a name of matched intent, empty if fallback has been used.
+	Intent   string
+	State    string
+	Response *Response
+}
+
+func New(def *Definition) (*Flow, error) {
+	if def == nil {
+		return nil, fmt.Errorf("flow: empty definition")
+	}
+	if _, ok := def.States[def.Initial]; !ok {
+		return nil, fmt.Errorf("flow: initial state %q is not defined", def.Initial)
+	}
+
+	f := &Flow{
+		initial: def.Initial,
+		states:  make(map[string]*compiledState, len(def.States)),
+		chats:   make(map[livechat.ChatID]string),
+	}
+
+	for name, state := range def.States {
+		compiled := &compiledState{fallback: state.Fallback}
+
+		for i, intent := range state.Intents {
+			if intent.Next != "" {
+				if _, ok := def.States[intent.Next]; !ok {
+					return nil, fmt.Errorf("flow: state %q: intent #%d moves to undefined state %q", name, i, intent.Next)
+				}
+			}
+
+			compiledIntent := &compiledIntent{Intent: intent}
+			switch intent.Match.Type {
+			case MatchRegex:
+				for _, value := range intent.Match.Values {
+					pattern, err := regexp.Compile(value)
+					if err != nil {
+						return nil, fmt.Errorf("flow: state %q: intent #%d: %w", name, i, err)
+					}
+					compiledIntent.patterns = append(compiledIntent.patterns, pattern)
+				}
+			case MatchFile, MatchForm:
+				for _, value := range intent.Match.Values {
+					if _, err := path.Match(value, ""); err != nil {
+						return nil, fmt.Errorf("flow: state %q: intent #%d: %w", name, i, err)
+					}
+				}
+			}
+
+			compiled.intents = append(compiled.intents, compiledIntent)
+		}
+
+		f.states[name] = compiled
+	}
+
+	return f, nil
+}
+
+// Adopt takes states of flow built with New, which must not be used
+// anymore. Conversations keep their state if it still exists in the new
+// flow, the rest starts over.
+func (f *Flow) Adopt(next *Flow) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.initial, f.states = next.initial, next.states
+	for chatID, state := range f.chats {
+		if _, ok := f.states[state]; !ok {
+			delete(f.chats, chatID)
+		}
+	}
+}
+
+// Handle matches message against intents of the chat's current state,
+// moves the chat to the next state and returns the answer. Returned reply
+// is nil if nothing matches and state has no fallback.
+func (f *Flow) Handle(chatID livechat.ChatID, text string) *Reply {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reply := f.match(chatID, func(intent *compiledIntent) bool {
+		return intent.Match.isText() && intent.matches(text)
+	})
+	if reply != nil {
+		return reply
+	}
+
+	stateName := f.current(chatID)
+	f.chats[chatID] = stateName
+	if fallback := f.states[stateName].fallback; fallback != nil {
+		return &Reply{State: stateName, Response: fallback}
+	}
+	return nil
+}
+
+// HandlePostback works like Handle but matches only postback intents
+// and never falls back.
+func (f *Flow) HandlePostback(chatID livechat.ChatID, postbackID string) *Reply {
+	return f.handleOnly(chatID, MatchPostback, postbackID)
+}
+
+// HandleFile matches content type of file uploaded by customer
+// against file intents, it never falls back.
+func (f *Flow) HandleFile(chatID livechat.ChatID, contentType string) *Reply {
+	return f.handleOnly(chatID, MatchFile, contentType)
+}
+
+// HandleForm matches type of form filled by customer against
+// form intents, it never falls back.
+func (f *Flow) HandleForm(chatID livechat.ChatID, formType string) *Reply {
+	return f.handleOnly(chatID, MatchForm, formType)
+}
+
+func (f *Flow) handleOnly(chatID livechat.ChatID, matchType MatchType, value string) *Reply {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.match(chatID, func(intent *compiledIntent) bool {
+		return intent.Match.Type == matchType && intent.matches(value)
+	})
+}
+
+func (f *Flow) match(chatID livechat.ChatID, matches func(*compiledIntent) bool) *Reply {
+	stateName := f.current(chatID)
+
+	for _, intent := range f.states[stateName].intents {
+		if !matches(intent) {
+			continue
+		}
+
+		if intent.Next != "" {
+			stateName = intent.Next
+		}
+		f.chats[chatID] = stateName
+
+		return &Reply{Intent: intent.Name, State: stateName, Response: &intent.Response}
+	}
+
+	return nil
+}
+
+func (f *Flow) current(chatID livechat.ChatID) string {
+	if state, ok := f.chats[chatID]; ok {
+		return state
+	}
+	return f.initial
+}
+
+// State returns current state of conversation.
+func (f *Flow) State(chatID livechat.ChatID) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.current(chatID)
+}
+
+// Forget removes conversation state, so the next message
+// starts from initial state.
+func (f *Flow) Forget(chatID livechat.ChatID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.chats, chatID)
+}
+
+func (i *compiledIntent) matches(text string) bool {
+	switch i.Match.Type {
+	case MatchExact:
+		for _, value := range i.Match.Values {
+			if equal(text, value, i.Match.CaseSensitive) {
+				return true
+			}
+		}
+	case MatchPostback:
+		for _, value := range i.Match.Values {
+			if text == value {
+				return true
+			}
+		}
+	case MatchKeyword:
+		words := strings.FieldsFunc(text, isSeparator)
+		for _, value := range i.Match.Values {
+			for _, word := range words {
+				if equal(word, value, i.Match.CaseSensitive) {
+					return true
+				}
+			}
+		}
+	case MatchRegex:
+		for _, pattern := range i.patterns {
+			if pattern.MatchString(text) {
+				return true
+			}
+		}
+	case MatchFile, MatchForm:
+		for _, value := range i.Match.Values {
+			if matched, _ := path.Match(strings.ToLower(value), strings.ToLower(text)); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isText reports whether intent matches text of messages.
+func (m *Match) isText() bool {
+	switch m.Type {
+	case MatchExact, MatchKeyword, MatchRegex:
+		return true
+	default:
+		return false
+	}
+}
+
+func equal(a, b string, caseSensitive bool) bool {
+	a = strings.TrimSpace(a)
+	if caseSensitive {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+}
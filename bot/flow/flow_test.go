@@ -0,0 +1,204 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+const definedChatID = livechat.ChatID("custom_chat_id")
+
+func Test_Flow_Default(t *testing.T) {
+	f, err := New(Default())
+	assert.NoError(t, err)
+
+	reply := f.Handle(definedChatID, "Hello")
+	assert.Equal(t, "hello", reply.Intent)
+	assert.Equal(t, "World!", reply.Response.Text)
+
+	reply = f.Handle(definedChatID, "hello")
+	assert.Equal(t, "", reply.Intent)
+	assert.Equal(t, "Wróć do człowieka", reply.Response.Buttons[0].Text)
+
+	reply = f.HandlePostback(definedChatID, reply.Response.Buttons[0].PostbackID)
+	assert.Equal(t, ActionTransfer, reply.Response.Action)
+	assert.Nil(t, f.Handle(definedChatID, "Wróć do człowieka"))
+
+	f.Forget(definedChatID)
+	reply = f.Handle(definedChatID, "Wróć do człowieka")
+	assert.Equal(t, ActionTransfer, reply.Response.Action)
+	assert.Nil(t, f.HandlePostback(definedChatID, "transfer_to_human"))
+}
+
+func Test_Flow_Postback(t *testing.T) {
+	f, err := New(&Definition{
+		Initial: "start",
+		States: map[string]*State{
+			"start": {
+				Intents: []*Intent{
+					{Name: "yes", Match: Match{Type: MatchPostback, Values: []string{"answer_yes"}}},
+				},
+				Fallback: &Response{Text: "Sorry?"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "yes", f.HandlePostback(definedChatID, "answer_yes").Intent)
+	assert.Nil(t, f.HandlePostback(definedChatID, "ANSWER_YES"))
+	assert.Nil(t, f.HandlePostback(definedChatID, "answer_no"))
+	assert.Equal(t, "", f.Handle(definedChatID, "answer_yes").Intent)
+}
+
+func Test_Flow_Matches(t *testing.T) {
+	f, err := New(&Definition{
+		Initial: "start",
+		States: map[string]*State{
+			"start": {Intents: []*Intent{
+				{Name: "exact", Match: Match{Type: MatchExact, Values: []string{"Hi", "Hey"}}},
+				{Name: "keyword", Match: Match{Type: MatchKeyword, Values: []string{"price"}}},
+				{Name: "regex", Match: Match{Type: MatchRegex, Values: []string{`^order #\d+$`}}},
+			}},
+		},
+	})
+	assert.NoError(t, err)
+
+	tests := map[string]string{
+		"hey ":                   "exact",
+		"What is the PRICE?":     "keyword",
+		"prices":                 "",
+		"order #1234":            "regex",
+		"my order #1234 is late": "",
+	}
+
+	for text, intent := range tests {
+		t.Run(text, func(t *testing.T) {
+			reply := f.Handle(definedChatID, text)
+			if intent == "" {
+				assert.Nil(t, reply)
+				return
+			}
+			assert.Equal(t, intent, reply.Intent)
+		})
+	}
+}
+
+func Test_Flow_Transitions(t *testing.T) {
+	f, err := New(&Definition{
+		Initial: "start",
+		States: map[string]*State{
+			"start": {Intents: []*Intent{
+				{Name: "order", Match: Match{Type: MatchKeyword, Values: []string{"order"}}, Next: "order_number"},
+			}},
+			"order_number": {
+				Intents: []*Intent{
+					{Name: "number", Match: Match{Type: MatchRegex, Values: []string{`^\d+$`}}, Response: Response{Text: "Thanks!"}, Next: "start"},
+				},
+				Fallback: &Response{Text: "Please, give me the number of your order."},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "order_number", f.Handle(definedChatID, "Where is my order?").State)
+	assert.Equal(t, "order_number", f.Handle(definedChatID, "I don't know").State)
+	assert.Equal(t, "start", f.Handle(definedChatID, "1234").State)
+
+	f.Handle(definedChatID, "order")
+	f.Forget(definedChatID)
+	assert.Equal(t, "start", f.State(definedChatID))
+}
+
+func Test_Flow_InvalidDefinition(t *testing.T) {
+	tests := map[string]*Definition{
+		"empty definition": nil,
+		"missing initial state": {
+			Initial: "start",
+			States:  map[string]*State{"other": {}},
+		},
+		"undefined transition": {
+			Initial: "start",
+			States: map[string]*State{"start": {Intents: []*Intent{
+				{Match: Match{Type: MatchExact, Values: []string{"a"}}, Next: "missing"},
+			}}},
+		},
+		"invalid regex": {
+			Initial: "start",
+			States: map[string]*State{"start": {Intents: []*Intent{
+				{Match: Match{Type: MatchRegex, Values: []string{"("}}},
+			}}},
+		},
+	}
+
+	for name, def := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(def)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_Flow_Adopt(t *testing.T) {
+	const otherChatID = livechat.ChatID("other_chat_id")
+
+	f, err := New(&Definition{
+		Initial: "start",
+		States: map[string]*State{
+			"start": {Intents: []*Intent{
+				{Name: "order", Match: Match{Type: MatchKeyword, Values: []string{"order"}}, Next: "order_number"},
+				{Name: "help", Match: Match{Type: MatchKeyword, Values: []string{"help"}}, Next: "help"},
+			}},
+			"order_number": {Fallback: &Response{Text: "Give me the number."}},
+			"help":         {Fallback: &Response{Text: "How can I help?"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	f.Handle(definedChatID, "order")
+	f.Handle(otherChatID, "help")
+
+	next, err := New(&Definition{
+		Initial: "start",
+		States: map[string]*State{
+			"start":        {},
+			"order_number": {Fallback: &Response{Text: "What is the number of your order?"}},
+		},
+	})
+	assert.NoError(t, err)
+	f.Adopt(next)
+
+	assert.Equal(t, "start", f.State(otherChatID))
+	assert.Equal(t, "What is the number of your order?", f.Handle(definedChatID, "1234").Response.Text)
+}
+
+func Test_Flow_FilesAndForms(t *testing.T) {
+	f, err := New(&Definition{
+		Initial: "start",
+		States: map[string]*State{
+			"start": {
+				Intents: []*Intent{
+					{Name: "image", Match: Match{Type: MatchFile, Values: []string{"image/*"}}, Response: Response{Text: "Nice picture!"}},
+					{Name: "survey", Match: Match{Type: MatchForm, Values: []string{"postchat"}}, Response: Response{Text: "Thanks!"}},
+				},
+				Fallback: &Response{Text: "Sorry?"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "image", f.HandleFile(definedChatID, "IMAGE/png").Intent)
+	assert.Nil(t, f.HandleFile(definedChatID, "application/pdf"))
+	assert.Equal(t, "survey", f.HandleForm(definedChatID, "postchat").Intent)
+	assert.Nil(t, f.HandleForm(definedChatID, "prechat"))
+	// file patterns never match text of messages
+	assert.Equal(t, "", f.Handle(definedChatID, "image/png").Intent)
+
+	_, err = New(&Definition{
+		Initial: "start",
+		States: map[string]*State{"start": {Intents: []*Intent{
+			{Match: Match{Type: MatchFile, Values: []string{"[image"}}},
+		}}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package handoff
+
+import (
+	"context"
+	"errors"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web"
+	log "github.com/sirupsen/logrus"
+)
+
+type Result string
+
+const (
+	// ResultTransferred means that the chat has been taken by a human.
+	ResultTransferred Result = "transferred"
+	// ResultQueued means that the chat waits in the queue of fallback group.
+	ResultQueued Result = "queued"
+	// ResultUnavailable means that nobody could take the chat.
+	ResultUnavailable Result = "unavailable"
+	// ResultFailed means that handoff could not be attempted at all.
+	ResultFailed Result = "failed"
+)
+
+type Reason string
+
+const (
+	ReasonOffline  Reason = "agent_offline"
+	ReasonAssigned Reason = "agent_assigned"
+	ReasonError    Reason = "error"
+)
+
+// Outcome describes the whole handoff of a single chat.
+type Outcome struct {
+	ChatID   livechat.ChatID
+	Strategy string
+	Result   Result
+	// Target is the agent or group which took the chat.
+	Target   *Target
+	Attempts []*Attempt
+	Err      error
+}
+
+type Attempt struct {
+	Target *Target
+	Reason Reason
+	Err    error
+}
+
+// Handed reports whether the chat left the bot.
+func (o *Outcome) Handed() bool {
+	return o.Result == ResultTransferred || o.Result == ResultQueued
+}
+
+func (o *Outcome) Fields() log.Fields {
+	fields := log.Fields{
+		"chat_id":  o.ChatID,
+		"strategy": o.Strategy,
+		"result":   o.Result,
+		"attempts": len(o.Attempts),
+	}
+	if o.Target != nil {
+		fields["target_type"] = o.Target.Type
+		fields["target_id"] = o.Target.String()
+	}
+	return fields
+}
+
+type Config struct {
+	Strategy string
+	// Groups are used by group strategy.
+	Groups []int
+	// QueueGroup is the group which chat is forced into (and waits
+	// in its queue) when no target accepts it. Disabled if nil.
+	QueueGroup *int
+}
+
+type Handoff struct {
+	client     web.LivechatRequests
+	strategy   Strategy
+	queueGroup *int
+}
+
+func New(client web.LivechatRequests, cfg *Config) (*Handoff, error) {
+	strategy, err := NewStrategy(cfg.Strategy, cfg.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handoff{
+		client:     client,
+		strategy:   strategy,
+		queueGroup: cfg.QueueGroup,
+	}, nil
+}
+
+// Transfer offers the chat to targets chosen by strategy one by one
+// and falls back to queueing if none of them accepts it.
+func (h *Handoff) Transfer(ctx context.Context, chatID livechat.ChatID) *Outcome {
+	outcome := &Outcome{ChatID: chatID, Strategy: h.strategy.Name(), Attempts: []*Attempt{}}
+
+	targets, err := h.strategy.Targets(ctx, h.client, chatID)
+	if err != nil {
+		outcome.Result = ResultFailed
+		outcome.Err = err
+		return outcome
+	}
+
+	for _, target := range targets {
+		if h.attempt(ctx, outcome, target, false) {
+			outcome.Result = ResultTransferred
+			return outcome
+		}
+	}
+
+	if h.queueGroup != nil {
+		target := &Target{Type: livechat.TransferTargetGroup, GroupID: *h.queueGroup}
+		if h.attempt(ctx, outcome, target, true) {
+			outcome.Result = ResultQueued
+			return outcome
+		}
+	}
+
+	outcome.Result = ResultUnavailable
+	return outcome
+}
+
+func (h *Handoff) attempt(ctx context.Context, outcome *Outcome, target *Target, force bool) bool {
+	_, err := h.client.TransferChat(ctx, buildTransferChatMessage(outcome.ChatID, target, force))
+	if err == nil {
+		outcome.Target = target
+		return true
+	}
+
+	attempt := &Attempt{Target: target, Reason: ReasonError, Err: err}
+	logEntry := log.WithError(err).WithField("chat_id", outcome.ChatID).WithField("target_id", target.String())
+
+	switch {
+	case errors.Is(err, web.ErrAgentOffline):
+		attempt.Reason = ReasonOffline
+		logEntry.Debug("Agent is offline")
+	case errors.Is(err, web.ErrAgentAlreadyInChat):
+		attempt.Reason = ReasonAssigned
+		logEntry.Debug("Agent is already assigned to chat")
+	default:
+		logEntry.Error("Cannot transfer chat on demand")
+	}
+
+	outcome.Attempts = append(outcome.Attempts, attempt)
+	return false
+}
+
+func buildTransferChatMessage(chatID livechat.ChatID, target *Target, force bool) *livechat.TransferChatRequest {
+	request := &livechat.TransferChatRequest{
+		ID:    chatID,
+		Force: force,
+	}
+
+	request.Target.Type = target.Type
+	if target.Type == livechat.TransferTargetGroup {
+		request.Target.GroupIDs = []int{target.GroupID}
+	} else {
+		request.Target.IDs = []livechat.AgentID{target.AgentID}
+	}
+
+	return request
+}
@@ -0,0 +1,140 @@
+package handoff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web"
+	"github.com/livechat/onboarding/livechat/web/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const definedChatID = livechat.ChatID("custom_chat_id")
+
+func Test_Handoff_LeastBusy(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("ListAgentsForTransfer", ctx, mock.Anything).Return([]*livechat.ListAgentsForTransferResponse{
+		{AgentID: "busy", TotalActiveChats: 5},
+		{AgentID: "idle", TotalActiveChats: 0},
+		{AgentID: "offline", TotalActiveChats: 0},
+	}, nil)
+	lcHTTP.On("TransferChat", ctx, helperMatchTarget("idle")).Return(nil, &web.APIError{Type: "validation", Message: "Agent is offline."})
+	lcHTTP.On("TransferChat", ctx, helperMatchTarget("offline")).Return(nil, &web.APIError{Type: "validation", Message: "Agent is offline."})
+	lcHTTP.On("TransferChat", ctx, helperMatchTarget("busy")).Return(&livechat.TransferChatResponse{}, nil)
+
+	outcome := helperCreateHandoff(t, lcHTTP, &Config{}).Transfer(ctx, definedChatID)
+
+	assert.Equal(t, ResultTransferred, outcome.Result)
+	assert.Equal(t, StrategyLeastBusy, outcome.Strategy)
+	assert.Equal(t, livechat.AgentID("busy"), outcome.Target.AgentID)
+	if assert.Len(t, outcome.Attempts, 2) {
+		assert.Equal(t, livechat.AgentID("idle"), outcome.Attempts[0].Target.AgentID)
+		assert.Equal(t, ReasonOffline, outcome.Attempts[0].Reason)
+	}
+}
+
+func Test_Handoff_RoundRobin(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("ListAgentsForTransfer", ctx, mock.Anything).Return([]*livechat.ListAgentsForTransferResponse{
+		{AgentID: "b"}, {AgentID: "a"},
+	}, nil)
+	lcHTTP.On("TransferChat", ctx, mock.Anything).Return(&livechat.TransferChatResponse{}, nil)
+
+	handoff := helperCreateHandoff(t, lcHTTP, &Config{Strategy: StrategyRoundRobin})
+
+	assert.Equal(t, livechat.AgentID("a"), handoff.Transfer(ctx, definedChatID).Target.AgentID)
+	assert.Equal(t, livechat.AgentID("b"), handoff.Transfer(ctx, definedChatID).Target.AgentID)
+	assert.Equal(t, livechat.AgentID("a"), handoff.Transfer(ctx, definedChatID).Target.AgentID)
+}
+
+func Test_Handoff_Group(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("TransferChat", ctx, mock.MatchedBy(func(r *livechat.TransferChatRequest) bool {
+		body, _ := json.Marshal(r.Target)
+		return string(body) == `{"type":"group","ids":[2]}`
+	})).Return(&livechat.TransferChatResponse{}, nil)
+
+	outcome := helperCreateHandoff(t, lcHTTP, &Config{Strategy: StrategyGroup, Groups: []int{2}}).Transfer(ctx, definedChatID)
+
+	assert.Equal(t, ResultTransferred, outcome.Result)
+	assert.Equal(t, 2, outcome.Target.GroupID)
+	lcHTTP.AssertNotCalled(t, "ListAgentsForTransfer", mock.Anything, mock.Anything)
+}
+
+func Test_Handoff_QueueFallback(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+	queue := 0
+
+	lcHTTP.On("ListAgentsForTransfer", ctx, mock.Anything).Return([]*livechat.ListAgentsForTransferResponse{}, nil)
+	lcHTTP.On("TransferChat", ctx, mock.MatchedBy(func(r *livechat.TransferChatRequest) bool {
+		return r.Force && r.Target.Type == livechat.TransferTargetGroup
+	})).Return(&livechat.TransferChatResponse{}, nil)
+
+	outcome := helperCreateHandoff(t, lcHTTP, &Config{QueueGroup: &queue}).Transfer(ctx, definedChatID)
+
+	assert.Equal(t, ResultQueued, outcome.Result)
+	assert.True(t, outcome.Handed())
+}
+
+func Test_Handoff_Unavailable(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("ListAgentsForTransfer", ctx, mock.Anything).Return([]*livechat.ListAgentsForTransferResponse{{AgentID: "a"}}, nil)
+	lcHTTP.On("TransferChat", ctx, mock.Anything).Return(nil, &web.APIError{Type: "validation", Message: "One or more of requested agents are already present in the chat"})
+
+	outcome := helperCreateHandoff(t, lcHTTP, &Config{}).Transfer(ctx, definedChatID)
+
+	assert.Equal(t, ResultUnavailable, outcome.Result)
+	assert.False(t, outcome.Handed())
+	if assert.Len(t, outcome.Attempts, 1) {
+		assert.Equal(t, ReasonAssigned, outcome.Attempts[0].Reason)
+	}
+}
+
+func Test_Handoff_Failed(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("ListAgentsForTransfer", ctx, mock.Anything).Return(nil, errors.New("timeout"))
+
+	outcome := helperCreateHandoff(t, lcHTTP, &Config{}).Transfer(ctx, definedChatID)
+
+	assert.Equal(t, ResultFailed, outcome.Result)
+	assert.Error(t, outcome.Err)
+}
+
+func Test_NewStrategy_Invalid(t *testing.T) {
+	_, err := NewStrategy("random", nil)
+	assert.Error(t, err)
+
+	_, err = NewStrategy(StrategyGroup, nil)
+	assert.Error(t, err)
+}
+
+func helperCreateHandoff(t *testing.T, lcHTTP *mocks.LivechatRequests, cfg *Config) *Handoff {
+	t.Helper()
+
+	handoff, err := New(lcHTTP, cfg)
+	if err != nil {
+		t.Fatalf("cannot create handoff: %s", err)
+	}
+	return handoff
+}
+
+func helperMatchTarget(agentID livechat.AgentID) interface{} {
+	return mock.MatchedBy(func(r *livechat.TransferChatRequest) bool {
+		return len(r.Target.IDs) == 1 && r.Target.IDs[0] == agentID
+	})
+}
@@ -0,0 +1,127 @@
+package handoff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web"
+)
+
+const (
+	StrategyLeastBusy  = "least_busy"
+	StrategyRoundRobin = "round_robin"
+	StrategyGroup      = "group"
+)
+
+// Target is a single transfer attempt: an agent or a group.
+type Target struct {
+	Type    string
+	AgentID livechat.AgentID
+	GroupID int
+}
+
+func (t *Target) String() string {
+	if t.Type == livechat.TransferTargetGroup {
+		return strconv.Itoa(t.GroupID)
+	}
+	return string(t.AgentID)
+}
+
+// Strategy decides which targets (and in what order) the chat
+// is offered to.
+type Strategy interface {
+	Name() string
+	Targets(context.Context, web.LivechatRequests, livechat.ChatID) ([]*Target, error)
+}
+
+// NewStrategy returns strategy registered under name.
+func NewStrategy(name string, groups []int) (Strategy, error) {
+	switch name {
+	case "", StrategyLeastBusy:
+		return &leastBusy{}, nil
+	case StrategyRoundRobin:
+		return &roundRobin{}, nil
+	case StrategyGroup:
+		if len(groups) == 0 {
+			return nil, fmt.Errorf("handoff: %s strategy requires at least one group", StrategyGroup)
+		}
+		return &groupStrategy{groups: groups}, nil
+	default:
+		return nil, fmt.Errorf("handoff: unknown strategy %q", name)
+	}
+}
+
+// leastBusy offers the chat to agents with the lowest number of active chats first.
+type leastBusy struct{}
+
+func (s *leastBusy) Name() string { return StrategyLeastBusy }
+
+func (s *leastBusy) Targets(ctx context.Context, client web.LivechatRequests, chatID livechat.ChatID) ([]*Target, error) {
+	agents, err := listAgents(ctx, client, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(agents, func(i, j int) bool {
+		return agents[i].TotalActiveChats < agents[j].TotalActiveChats
+	})
+	return agentTargets(agents), nil
+}
+
+// roundRobin offers every next chat to the next agent (ordered by ID).
+type roundRobin struct {
+	next uint64
+}
+
+func (s *roundRobin) Name() string { return StrategyRoundRobin }
+
+func (s *roundRobin) Targets(ctx context.Context, client web.LivechatRequests, chatID livechat.ChatID) ([]*Target, error) {
+	agents, err := listAgents(ctx, client, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(agents) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].AgentID < agents[j].AgentID })
+	offset := int((atomic.AddUint64(&s.next, 1) - 1) % uint64(len(agents)))
+
+	return agentTargets(append(agents[offset:], agents[:offset]...)), nil
+}
+
+// groupStrategy transfers the chat to configured groups, LiveChat
+// picks an agent inside the group.
+type groupStrategy struct {
+	groups []int
+}
+
+func (s *groupStrategy) Name() string { return StrategyGroup }
+
+func (s *groupStrategy) Targets(ctx context.Context, client web.LivechatRequests, chatID livechat.ChatID) ([]*Target, error) {
+	targets := []*Target{}
+	for _, group := range s.groups {
+		targets = append(targets, &Target{Type: livechat.TransferTargetGroup, GroupID: group})
+	}
+	return targets, nil
+}
+
+func listAgents(ctx context.Context, client web.LivechatRequests, chatID livechat.ChatID) ([]*livechat.ListAgentsForTransferResponse, error) {
+	agents, err := client.ListAgentsForTransfer(ctx, &livechat.ListAgentsForTransferRequest{ChatID: chatID})
+	if err != nil {
+		return nil, fmt.Errorf("handoff: cannot fetch list of agents: %w", err)
+	}
+	return agents, nil
+}
+
+func agentTargets(agents []*livechat.ListAgentsForTransferResponse) []*Target {
+	targets := []*Target{}
+	for _, agent := range agents {
+		targets = append(targets, &Target{Type: livechat.TransferTargetAgent, AgentID: agent.AgentID})
+	}
+	return targets
+}
@@ -3,6 +3,8 @@ package bot
 import (
 	"context"
 
+	"github.com/livechat/onboarding/bot/flow"
+	"github.com/livechat/onboarding/bot/handoff"
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/auth"
 )
@@ -13,12 +15,27 @@ type BotManager interface {
 	// and fetch existing (or create a new one) bots (agents).
 	InstallApp(context.Context, livechat.LicenseID) error
 	// UninstallApp removes the whole memory footprint and cancel
-	// existing connections to LiveChat.
+	// existing connections to LiveChat. License is removed even if
+	// its cleanup in LiveChat fails, the returned error tells so.
 	UninstallApp(context.Context, livechat.LicenseID) error
 	// Destroy does everything what UninstallApp but for every license.
 	Destroy(context.Context)
+
+	Admin
 }
 
 type Sender interface {
 	Talk(context.Context, livechat.ChatID, *livechat.PushIncomingMessage) error
+	// Postback reacts to the button of rich message clicked by customer.
+	Postback(context.Context, livechat.ChatID, *livechat.PushIncomingRichMessagePostback) error
+	// Forget drops conversation state of chat which bot no longer serves.
+	Forget(livechat.ChatID)
+}
+
+// ReloadableSender can swap its configuration while it is running.
+type ReloadableSender interface {
+	Sender
+	// Reload replaces conversation flow (built with flow.New) and
+	// handoff at once.
+	Reload(*flow.Flow, *handoff.Handoff)
 }
@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrFull   = errors.New("queue: worker backlog is full")
+	ErrClosed = errors.New("queue: closed")
+)
+
+// Job is a piece of work done in background, ctx is the one given to New.
+type Job func(ctx context.Context)
+
+type Config struct {
+	// Workers started for every license.
+	Workers int
+	// Size of backlog of every worker, jobs above it are rejected.
+	Size int
+}
+
+var DefaultConfig = Config{Workers: 4, Size: 64}
+
+// Queue runs jobs in a pool of workers started for every license.
+// Jobs of the same chat are always run by the same worker, so they
+// are done one by one in order they have been pushed.
+type Queue struct {
+	ctx context.Context
+	cfg Config
+
+	mu      sync.Mutex
+	closed  bool
+	pools   map[livechat.LicenseID][]chan Job
+	running sync.WaitGroup
+}
+
+func New(ctx context.Context, cfg *Config) *Queue {
+	q := &Queue{
+		ctx:   ctx,
+		cfg:   DefaultConfig,
+		pools: make(map[livechat.LicenseID][]chan Job),
+	}
+	if cfg != nil && cfg.Workers > 0 {
+		q.cfg.Workers = cfg.Workers
+	}
+	if cfg != nil && cfg.Size > 0 {
+		q.cfg.Size = cfg.Size
+	}
+
+	return q
+}
+
+// Push adds job to the backlog of chat's worker. It never blocks,
+// ErrFull is returned if the worker is too far behind.
+func (q *Queue) Push(licenseID livechat.LicenseID, chatID livechat.ChatID, job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	jobs := q.pool(licenseID)[index(chatID, q.cfg.Workers)]
+	select {
+	case jobs <- job:
+		metrics.QueuedJobs.WithLabelValues(strconv.Itoa(int(licenseID))).Inc()
+		return nil
+	default:
+		return fmt.Errorf("%w (license id: %v, chat id: %v)", ErrFull, licenseID, chatID)
+	}
+}
+
+// Remove stops workers of license (e.g. once it is uninstalled), jobs
+// already pushed are still done. Workers are started again on next Push.
+func (q *Queue) Remove(licenseID livechat.LicenseID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pool, ok := q.pools[licenseID]
+	if !ok || q.closed {
+		return
+	}
+	for _, jobs := range pool {
+		close(jobs)
+	}
+	delete(q.pools, licenseID)
+}
+
+// Drain stops accepting jobs and waits until every pushed job is done.
+func (q *Queue) Drain(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		for _, pool := range q.pools {
+			for _, jobs := range pool {
+				close(jobs)
+			}
+		}
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("queue: drain: %w", ctx.Err())
+	}
+}
+
+// pool returns workers of license, starting them if needed. It has to be
+// called with mutex locked.
+func (q *Queue) pool(licenseID livechat.LicenseID) []chan Job {
+	if pool, ok := q.pools[licenseID]; ok {
+		return pool
+	}
+
+	pool := make([]chan Job, q.cfg.Workers)
+	for i := range pool {
+		pool[i] = make(chan Job, q.cfg.Size)
+
+		q.running.Add(1)
+		go q.work(licenseID, pool[i])
+	}
+	q.pools[licenseID] = pool
+
+	return pool
+}
+
+func (q *Queue) work(licenseID livechat.LicenseID, jobs <-chan Job) {
+	defer q.running.Done()
+
+	depth := metrics.QueuedJobs.WithLabelValues(strconv.Itoa(int(licenseID)))
+	for job := range jobs {
+		q.run(licenseID, job)
+		depth.Dec()
+	}
+}
+
+func (q *Queue) run(licenseID livechat.LicenseID, job Job) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.WithField("license_id", licenseID).WithField("recover", err).Error("Queued job panicked")
+		}
+	}()
+
+	job(q.ctx)
+}
+
+func index(chatID livechat.ChatID, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(chatID))
+	return int(h.Sum32() % uint32(workers))
+}
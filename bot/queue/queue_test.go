@@ -0,0 +1,112 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+const definedLicenseID = livechat.LicenseID(1234)
+
+func Test_Queue_KeepsChatOrder(t *testing.T) {
+	q := New(context.Background(), &Config{Workers: 3, Size: 100})
+
+	var mu sync.Mutex
+	handled := map[livechat.ChatID][]int{}
+	chats := []livechat.ChatID{"chat_1", "chat_2", "chat_3", "chat_4"}
+
+	for i := 0; i < 20; i++ {
+		for _, chatID := range chats {
+			i, chatID := i, chatID
+			assert.NoError(t, q.Push(definedLicenseID, chatID, func(context.Context) {
+				mu.Lock()
+				defer mu.Unlock()
+				handled[chatID] = append(handled[chatID], i)
+			}))
+		}
+	}
+	assert.NoError(t, q.Drain(context.Background()))
+
+	for _, chatID := range chats {
+		if assert.Len(t, handled[chatID], 20) {
+			for i, got := range handled[chatID] {
+				assert.Equal(t, i, got)
+			}
+		}
+	}
+}
+
+func Test_Queue_Backpressure(t *testing.T) {
+	q := New(context.Background(), &Config{Workers: 1, Size: 1})
+
+	started, release := make(chan bool), make(chan bool)
+	assert.NoError(t, q.Push(definedLicenseID, "chat_1", func(context.Context) {
+		started <- true
+		<-release
+	}))
+	<-started
+
+	assert.NoError(t, q.Push(definedLicenseID, "chat_1", func(context.Context) {}))
+	err := q.Push(definedLicenseID, "chat_2", func(context.Context) {})
+	assert.True(t, errors.Is(err, ErrFull))
+
+	// other licenses have their own workers
+	assert.NoError(t, q.Push(4321, "chat_1", func(context.Context) {}))
+
+	close(release)
+	assert.NoError(t, q.Drain(context.Background()))
+}
+
+func Test_Queue_Drain(t *testing.T) {
+	q := New(context.Background(), &Config{Workers: 1, Size: 1})
+
+	release := make(chan bool)
+	done := false
+	assert.NoError(t, q.Push(definedLicenseID, "chat_1", func(context.Context) {
+		<-release
+		done = true
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(q.Drain(ctx), context.DeadlineExceeded))
+	assert.True(t, errors.Is(q.Push(definedLicenseID, "chat_1", func(context.Context) {}), ErrClosed))
+
+	close(release)
+	assert.NoError(t, q.Drain(context.Background()))
+	assert.True(t, done)
+}
+
+func Test_Queue_RecoversPanic(t *testing.T) {
+	q := New(context.Background(), &Config{Workers: 1})
+
+	done := false
+	assert.NoError(t, q.Push(definedLicenseID, "chat_1", func(context.Context) { panic("boom") }))
+	assert.NoError(t, q.Push(definedLicenseID, "chat_1", func(context.Context) { done = true }))
+	assert.NoError(t, q.Drain(context.Background()))
+	assert.True(t, done)
+}
+
+func Test_Queue_Remove(t *testing.T) {
+	q := New(context.Background(), &Config{Workers: 2})
+
+	done := make(chan bool, 2)
+	assert.NoError(t, q.Push(definedLicenseID, "chat_1", func(context.Context) { done <- true }))
+	q.Remove(definedLicenseID)
+	q.Remove(definedLicenseID)
+	assert.True(t, <-done)
+
+	q.mu.Lock()
+	assert.NotContains(t, q.pools, definedLicenseID)
+	q.mu.Unlock()
+
+	// workers are started again for license installed once more
+	assert.NoError(t, q.Push(definedLicenseID, "chat_1", func(context.Context) { done <- true }))
+	assert.NoError(t, q.Drain(context.Background()))
+	assert.True(t, <-done)
+}
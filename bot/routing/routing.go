@@ -0,0 +1,205 @@
+package routing
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+// Rule picks bots for chats matching all of its conditions.
+// Rule without conditions matches every chat.
+type Rule struct {
+	// Groups matches chats which belong to any of the groups.
+	Groups []int `json:"groups,omitempty"`
+	// Customer matches attributes of customer by glob pattern (case
+	// insensitive), e.g. "email" => "*@example.com". Attribute is "name",
+	// "email", "session.<key>" for session field or "survey.<label>"
+	// for answer of pre-chat survey.
+	Customer map[string]string `json:"customer,omitempty"`
+	// Properties matches chat properties named "namespace.name" by glob pattern.
+	Properties map[string]string `json:"properties,omitempty"`
+
+	// Bots allowed to take the chat, every bot if empty. Bots are named by
+	// index in order they have been added to the license (the first one is 0),
+	// since their IDs are created at install and differ between licenses.
+	// Only bots enabled on install (bot.capacity.min_bots) exist from the start.
+	Bots []int `json:"bots,omitempty"`
+	// Skip leaves the chat for humans.
+	Skip bool `json:"skip,omitempty"`
+}
+
+// Decision tells which bots may take the chat.
+type Decision struct {
+	// Rule is the index of matched rule, -1 if none matched.
+	Rule int
+	Bots []int
+	Skip bool
+}
+
+// Allows reports whether bot (by its index) may take the chat.
+func (d *Decision) Allows(index int) bool {
+	if d.Skip {
+		return false
+	}
+	if len(d.Bots) == 0 {
+		return true
+	}
+	for _, bot := range d.Bots {
+		if bot == index {
+			return true
+		}
+	}
+	return false
+}
+
+// Router matches chats against rules in order, the first matching
+// rule wins. Every bot may take the chat if no rule matches.
+type Router struct {
+	mu    sync.RWMutex
+	rules []*Rule
+}
+
+func New(rules []*Rule) (*Router, error) {
+	if err := validate(rules); err != nil {
+		return nil, err
+	}
+	return &Router{rules: rules}, nil
+}
+
+// Adopt takes rules of router built with New.
+func (r *Router) Adopt(next *Router) {
+	next.mu.RLock()
+	rules := next.rules
+	next.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = rules
+}
+
+func (r *Router) Route(chat *livechat.Chat) *Decision {
+	if r == nil {
+		return &Decision{Rule: -1}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i, rule := range r.rules {
+		if rule.matches(chat) {
+			return &Decision{Rule: i, Bots: rule.Bots, Skip: rule.Skip}
+		}
+	}
+	return &Decision{Rule: -1}
+}
+
+func (r *Rule) matches(chat *livechat.Chat) bool {
+	if len(r.Groups) > 0 && !intersects(r.Groups, chat.GroupIDs()) {
+		return false
+	}
+
+	for name, pattern := range r.Properties {
+		value, ok := chat.Properties.Get(name)
+		if !ok || !match(pattern, value) {
+			return false
+		}
+	}
+
+	if len(r.Customer) == 0 {
+		return true
+	}
+	customer := chat.Customer()
+	if customer == nil {
+		return false
+	}
+	for attribute, pattern := range r.Customer {
+		value, ok := customerAttribute(chat, customer, attribute)
+		if !ok || !match(pattern, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func customerAttribute(chat *livechat.Chat, customer *livechat.User, attribute string) (string, bool) {
+	switch {
+	case attribute == "name":
+		return customer.Name, customer.Name != ""
+	case attribute == "email":
+		return customer.Email, customer.Email != ""
+	case strings.HasPrefix(attribute, "session."):
+		return customer.SessionField(strings.TrimPrefix(attribute, "session."))
+	case strings.HasPrefix(attribute, "survey."):
+		if chat.Thread == nil {
+			return "", false
+		}
+		value, ok := chat.Thread.Survey()[strings.TrimPrefix(attribute, "survey.")]
+		return value, ok
+	default:
+		return "", false
+	}
+}
+
+func match(pattern, value string) bool {
+	matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return matched
+}
+
+func intersects(a, b []int) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validate(rules []*Rule) error {
+	for i, rule := range rules {
+		if rule == nil {
+			return fmt.Errorf("routing: rule #%d is empty", i)
+		}
+		if rule.Skip && len(rule.Bots) > 0 {
+			return fmt.Errorf("routing: rule #%d: skipped chat cannot be routed to bots", i)
+		}
+		for _, bot := range rule.Bots {
+			if bot < 0 {
+				return fmt.Errorf("routing: rule #%d: bot index %d is negative", i, bot)
+			}
+		}
+		for attribute, pattern := range rule.Customer {
+			if !isCustomerAttribute(attribute) {
+				return fmt.Errorf("routing: rule #%d: unknown customer attribute %q", i, attribute)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("routing: rule #%d: customer %q: %w", i, attribute, err)
+			}
+		}
+		for name, pattern := range rule.Properties {
+			if !strings.Contains(name, ".") {
+				return fmt.Errorf("routing: rule #%d: property %q has to be named \"namespace.name\"", i, name)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("routing: rule #%d: property %q: %w", i, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func isCustomerAttribute(attribute string) bool {
+	switch {
+	case attribute == "name", attribute == "email":
+		return true
+	case strings.HasPrefix(attribute, "session."), strings.HasPrefix(attribute, "survey."):
+		return true
+	default:
+		return false
+	}
+}
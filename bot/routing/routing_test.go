@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Router_Route(t *testing.T) {
+	router, err := New([]*Rule{
+		{Groups: []int{5}, Skip: true},
+		{Customer: map[string]string{"survey.Department": "sales"}, Bots: []int{0}},
+		{Customer: map[string]string{"email": "*@example.com", "session.plan": "premium"}, Bots: []int{1}},
+		{Properties: map[string]string{"routing.language": "pl"}, Bots: []int{0, 2}},
+	})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		chat string
+		rule int
+	}{
+		"no rule":            {`{"id": "chat_1"}`, -1},
+		"group":              {`{"id": "chat_1", "access": {"group_ids": [0, 5]}}`, 0},
+		"pre-chat survey":    {helperChatWithSurvey(`{"type": "select", "label": "Department", "answer": {"id": "1", "label": "Sales"}}`), 1},
+		"customer":           {`{"id": "chat_1", "users": [{"id": "c", "type": "customer", "email": "JOHN@example.com", "session_fields": [{"plan": "premium"}]}]}`, 2},
+		"customer (partial)": {`{"id": "chat_1", "users": [{"id": "c", "type": "customer", "email": "john@example.com"}]}`, -1},
+		"agent is ignored":   {`{"id": "chat_1", "users": [{"id": "a", "type": "agent", "email": "john@example.com", "session_fields": [{"plan": "premium"}]}]}`, -1},
+		"property":           {`{"id": "chat_1", "properties": {"routing": {"language": "PL"}}}`, 3},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var chat livechat.Chat
+			if err := json.Unmarshal([]byte(tt.chat), &chat); err != nil {
+				t.Fatalf("cannot decode chat: %s", err)
+			}
+
+			assert.Equal(t, tt.rule, router.Route(&chat).Rule)
+		})
+	}
+}
+
+func Test_Decision_Allows(t *testing.T) {
+	assert.True(t, (&Decision{}).Allows(0))
+	assert.False(t, (&Decision{Skip: true}).Allows(0))
+	assert.True(t, (&Decision{Bots: []int{1}}).Allows(1))
+	assert.False(t, (&Decision{Bots: []int{1}}).Allows(0))
+
+	var router *Router
+	assert.Equal(t, -1, router.Route(&livechat.Chat{}).Rule)
+}
+
+func Test_Router_InvalidRules(t *testing.T) {
+	tests := map[string]*Rule{
+		"skip with bots":    {Skip: true, Bots: []int{0}},
+		"negative bot":      {Bots: []int{-1}},
+		"unknown attribute": {Customer: map[string]string{"phone": "*"}},
+		"invalid pattern":   {Customer: map[string]string{"email": "[a-"}},
+		"property name":     {Properties: map[string]string{"language": "pl"}},
+	}
+
+	for name, rule := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New([]*Rule{rule})
+			assert.Error(t, err)
+		})
+	}
+
+	router, _ := New(nil)
+	assert.Equal(t, -1, router.Route(&livechat.Chat{}).Rule)
+
+	next, _ := New([]*Rule{{Skip: true}})
+	router.Adopt(next)
+	assert.True(t, router.Route(&livechat.Chat{}).Skip)
+}
+
+func helperChatWithSurvey(field string) string {
+	return `{"id": "chat_1", "users": [{"id": "c", "type": "customer"}], "thread": {"id": "t", "events": [
+		{"id": "e", "type": "filled_form", "form_type": "prechat", "fields": [` + field + `]}
+	]}}`
+}
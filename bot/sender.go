@@ -2,89 +2,188 @@ package bot
 
 import (
 	"context"
-	"strings"
+	"sync"
 
+	"github.com/livechat/onboarding/bot/flow"
+	"github.com/livechat/onboarding/bot/handoff"
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/web"
+	"github.com/livechat/onboarding/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
 type sender struct {
 	client      web.LivechatRequests
 	appAuthorID string
+
+	// mu makes sure reply is picked by flow and handed off with
+	// handoff of the same configuration, Reload swaps them at once.
+	// Flow itself is never replaced, it adopts the new one in place.
+	mu      sync.RWMutex
+	flow    *flow.Flow
+	handoff *handoff.Handoff
 }
 
-func NewSender(client web.LivechatRequests, authorID string) Sender {
-	return &sender{client: client, appAuthorID: authorID}
+func NewSender(client web.LivechatRequests, authorID string, conversation *flow.Flow, transfer *handoff.Handoff) ReloadableSender {
+	return &sender{client: client, appAuthorID: authorID, flow: conversation, handoff: transfer}
 }
 
+// Talk answers messages, files and forms sent by customer. Other
+// events (e.g. system messages) are ignored.
 func (s *sender) Talk(ctx context.Context, chatID livechat.ChatID, msg *livechat.PushIncomingMessage) error {
-	if msg.Payload.Event.Type != "message" {
+	event := msg.Payload.Event.ChatEvent
+	if event == nil || event.Meta().AuthorID == s.appAuthorID {
 		return nil
 	}
-	if msg.Payload.Event.AuthorID == s.appAuthorID {
+
+	var handle func(*flow.Flow) *flow.Reply
+	switch event := event.(type) {
+	case *livechat.MessageEvent:
+		handle = func(f *flow.Flow) *flow.Reply { return f.Handle(chatID, event.Text) }
+	case *livechat.FileEvent:
+		handle = func(f *flow.Flow) *flow.Reply { return f.HandleFile(chatID, event.ContentType) }
+	case *livechat.FilledFormEvent:
+		handle = func(f *flow.Flow) *flow.Reply { return f.HandleForm(chatID, event.FormType) }
+	default:
 		return nil
 	}
+	reply, transfer := s.decide(handle)
 
-	switch msg.Payload.Event.Text {
-	case "Hello":
-		_, err := s.client.SendEvent(ctx, livechat.BuildMessage(chatID, "World!"))
-		return err
-	case "Wróć do człowieka":
-		return s.redirectToAgent(ctx, chatID)
-	default:
-		_, err := s.client.SendEvent(ctx, livechat.BuildButtonMessage(chatID, "Czy chcesz wrócić do człowieka?", "", "Wróć do człowieka"))
-		return err
+	logEntry := log.WithField("chat_id", chatID).WithField("event_type", event.Meta().Type)
+	if reply == nil {
+		logEntry.WithField("state", s.flow.State(chatID)).Debug("Event does not match any intent")
+		return nil
 	}
+
+	logEntry.WithFields(log.Fields{
+		"intent": reply.Intent,
+		"state":  reply.State,
+	}).Debug("Replying to event")
+
+	return s.respond(ctx, chatID, reply.Response, transfer)
 }
 
-func (s *sender) redirectToAgent(ctx context.Context, chatID livechat.ChatID) error {
-	realAgents, err := s.client.ListAgentsForTransfer(ctx, &livechat.ListAgentsForTransferRequest{ChatID: chatID})
-	if err != nil {
-		log.WithError(err).Error("Cannot fetch list of real agents")
-		return err
+func (s *sender) Postback(ctx context.Context, chatID livechat.ChatID, msg *livechat.PushIncomingRichMessagePostback) error {
+	if msg.Payload.UserID == s.appAuthorID {
+		return nil
 	}
 
-	if len(realAgents) == 0 {
-		_, err = s.client.SendEvent(ctx, livechat.BuildMessage(chatID, "Obecnie nie ma żadnego człowieka do rozmowy :("))
-		return err
+	reply, transfer := s.decide(func(f *flow.Flow) *flow.Reply {
+		return f.HandlePostback(chatID, msg.Payload.Postback.ID)
+	})
+	if reply == nil {
+		log.WithField("chat_id", chatID).WithField("postback_id", msg.Payload.Postback.ID).Debug("Postback does not match any intent")
+		return nil
 	}
 
-	for _, realAgent := range realAgents {
-		if _, err = s.client.TransferChat(ctx, buildTransferChatMessage(chatID, realAgent.AgentID)); err != nil {
-			if isAgentOffline(err) || isAgentAssigned(err) {
-				log.WithError(err).WithField("chat_id", chatID).Debug("Agent is offline or already assigned to chat")
-				continue
-			}
+	log.WithFields(log.Fields{
+		"chat_id":     chatID,
+		"intent":      reply.Intent,
+		"state":       reply.State,
+		"postback_id": msg.Payload.Postback.ID,
+	}).Debug("Replying to postback")
+
+	return s.respond(ctx, chatID, reply.Response, transfer)
+}
+
+// decide picks reply with handle and returns it together with handoff
+// which belongs to the same configuration as the flow.
+func (s *sender) decide(handle func(*flow.Flow) *flow.Reply) (*flow.Reply, *handoff.Handoff) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return handle(s.flow), s.handoff
+}
+
+func (s *sender) Forget(chatID livechat.ChatID) {
+	s.flow.Forget(chatID)
+}
+
+func (s *sender) Reload(conversation *flow.Flow, transfer *handoff.Handoff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-			log.WithError(err).WithField("chat_id", chatID).Error("Cannot transfer chat to agent on demand")
-			continue
+	s.flow.Adopt(conversation)
+	s.handoff = transfer
+}
+
+func (s *sender) respond(ctx context.Context, chatID livechat.ChatID, response *flow.Response, transfer *handoff.Handoff) error {
+	if response.Text != "" {
+		if _, err := s.client.SendEvent(ctx, livechat.BuildMessage(chatID, response.Text)); err != nil {
+			return err
+		}
+	}
+	if response.Title != "" || response.Image != "" || len(response.Buttons) > 0 {
+		event, err := buildCard(chatID, response)
+		if err != nil {
+			return err
+		}
+		if _, err := s.client.SendEvent(ctx, event); err != nil {
+			return err
 		}
+	}
 
+	switch response.Action {
+	case flow.ActionTransfer:
+		transferred, err := s.redirectToAgent(ctx, chatID, transfer)
+		if !transferred {
+			// Nobody took the chat, so the conversation with bot starts over.
+			s.flow.Forget(chatID)
+		}
+		return err
+	default:
 		return nil
 	}
-
-	_, err = s.client.SendEvent(ctx, livechat.BuildMessage(chatID, "Obecnie nie ma żadnego człowieka do rozmowy :("))
-	return err
 }
 
-func buildTransferChatMessage(chatID livechat.ChatID, agentID ...livechat.AgentID) *livechat.TransferChatRequest {
-	return &livechat.TransferChatRequest{
-		ID: chatID,
-		Target: struct {
-			Type string             "json:\"type\""
-			IDs  []livechat.AgentID "json:\"ids\""
-		}{
-			Type: "agent",
-			IDs:  agentID,
-		},
-		Force: false,
+func (s *sender) redirectToAgent(ctx context.Context, chatID livechat.ChatID, transfer *handoff.Handoff) (bool, error) {
+	outcome := transfer.Transfer(ctx, chatID)
+	logEntry := log.WithFields(outcome.Fields())
+
+	metrics.Handoffs.WithLabelValues(outcome.Strategy, string(outcome.Result)).Inc()
+	for _, attempt := range outcome.Attempts {
+		metrics.TransferAttempts.WithLabelValues(outcome.Strategy, string(attempt.Reason)).Inc()
+	}
+	if outcome.Target != nil {
+		metrics.TransferAttempts.WithLabelValues(outcome.Strategy, "accepted").Inc()
 	}
-}
 
-func isAgentOffline(err error) bool {
-	return strings.Contains(err.Error(), "Agent is offline.")
+	switch outcome.Result {
+	case handoff.ResultTransferred:
+		logEntry.Info("Chat handed off to human")
+		return true, nil
+	case handoff.ResultQueued:
+		logEntry.Info("Chat queued for human")
+		_, err := s.client.SendEvent(ctx, livechat.BuildMessage(chatID, "Wszyscy są teraz zajęci, ale ktoś odpowie tak szybko, jak to możliwe."))
+		return true, err
+	case handoff.ResultFailed:
+		logEntry.WithError(outcome.Err).Error("Cannot hand off chat to human")
+		return false, outcome.Err
+	default:
+		logEntry.Info("Nobody is available to take over chat")
+		_, err := s.client.SendEvent(ctx, livechat.BuildMessage(chatID, "Obecnie nie ma żadnego człowieka do rozmowy :("))
+		return false, err
+	}
 }
-func isAgentAssigned(err error) bool {
-	return strings.Contains(err.Error(), "One or more of requested agents are already present in the chat")
+
+func buildCard(chatID livechat.ChatID, response *flow.Response) (*livechat.Event, error) {
+	builder := livechat.NewCards(chatID).Card(response.Title, response.Subtitle)
+	if response.Image != "" {
+		builder.Image(response.Image)
+	}
+
+	for _, button := range response.Buttons {
+		switch button.Type {
+		case livechat.ButtonTypeUrl:
+			builder.Button(livechat.URLButton(button.Text, button.Value, button.PostbackID))
+		case livechat.ButtonTypePhone:
+			builder.Button(livechat.PhoneButton(button.Text, button.Value, button.PostbackID))
+		case livechat.ButtonTypeWebView:
+			builder.Button(livechat.WebViewButton(button.Text, button.Value, livechat.WebviewHeightTall, button.PostbackID))
+		default:
+			builder.Button(livechat.MessageButton(button.Text, button.PostbackID))
+		}
+	}
+
+	return builder.Build()
 }
@@ -2,11 +2,15 @@ package bot
 
 import (
 	"context"
-	"errors"
 	"testing"
 
+	"github.com/livechat/onboarding/bot/flow"
+	"github.com/livechat/onboarding/bot/handoff"
 	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web"
 	"github.com/livechat/onboarding/livechat/web/mocks"
+	"github.com/livechat/onboarding/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -25,13 +29,28 @@ func Test_Sender_Hello(t *testing.T) {
 		return p.Event.Text == "World!"
 	})).Return(&livechat.SendEventResponse{}, nil)
 
-	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID)
-	msg.Payload.Event.Text = "Hello"
+	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID, "Hello")
 
-	sender := NewSender(lcHTTP, definedAuthorID)
+	sender := helperCreateSender(t, lcHTTP)
 	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
 }
 
+func Test_Sender_Fallback(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("SendEvent", ctx, mock.MatchedBy(func(p *livechat.Event) bool {
+		card := p.Event.Elements[0]
+		return p.Event.TemplateID == livechat.TemplateCards && card.Image == nil && card.Button[0].PostbackID == "transfer_to_human"
+	})).Return(&livechat.SendEventResponse{}, nil)
+
+	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID, "Something else")
+
+	sender := helperCreateSender(t, lcHTTP)
+	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
+	lcHTTP.AssertNumberOfCalls(t, "SendEvent", 1)
+}
+
 func Test_Sender_Transfer_AgentOffline(t *testing.T) {
 	lcHTTP := new(mocks.LivechatRequests)
 	ctx := context.Background()
@@ -46,12 +65,11 @@ func Test_Sender_Transfer_AgentOffline(t *testing.T) {
 
 	lcHTTP.On("TransferChat", ctx, mock.MatchedBy(func(p *livechat.TransferChatRequest) bool {
 		return p.Target.IDs[0] == "abcd"
-	})).Return(nil, errors.New("Agent is offline."))
+	})).Return(nil, &web.APIError{Type: "validation", Message: "Agent is offline."})
 
-	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID)
-	msg.Payload.Event.Text = "Wróć do człowieka"
+	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID, "Wróć do człowieka")
 
-	sender := NewSender(lcHTTP, definedAuthorID)
+	sender := helperCreateSender(t, lcHTTP)
 	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
 	lcHTTP.AssertNumberOfCalls(t, "TransferChat", 1)
 	lcHTTP.AssertNumberOfCalls(t, "SendEvent", 1)
@@ -67,37 +85,127 @@ func Test_Sender_Transfer_AgentOnline(t *testing.T) {
 
 	lcHTTP.On("TransferChat", ctx, mock.Anything).Return(&livechat.TransferChatResponse{}, nil)
 
-	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID)
-	msg.Payload.Event.Text = "Wróć do człowieka"
+	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID, "Wróć do człowieka")
+
+	handoffs := testutil.ToFloat64(metrics.Handoffs.WithLabelValues("least_busy", "transferred"))
+
+	sender := helperCreateSender(t, lcHTTP)
+	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
+	lcHTTP.AssertNumberOfCalls(t, "TransferChat", 1)
+	assert.Equal(t, handoffs+1, testutil.ToFloat64(metrics.Handoffs.WithLabelValues("least_busy", "transferred")))
+}
+
+func Test_Sender_Postback_Transfer(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("ListAgentsForTransfer", ctx, mock.Anything).Return([]*livechat.ListAgentsForTransferResponse{{
+		AgentID: "abcd",
+	}}, nil)
+	lcHTTP.On("TransferChat", ctx, mock.Anything).Return(&livechat.TransferChatResponse{}, nil)
+
+	postback := &livechat.PushIncomingRichMessagePostback{Action: "incoming_rich_message_postback", LicenseID: definedLicenseID}
+	postback.Payload.ChatID = definedChatID
+	postback.Payload.UserID = "customer_id"
+	postback.Payload.Postback.ID = "transfer_to_human"
+
+	// message echoed by clicked button
+	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID, "Wróć do człowieka")
+
+	sender := helperCreateSender(t, lcHTTP)
+	assert.NoError(t, sender.Postback(ctx, definedChatID, postback))
+	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
+	lcHTTP.AssertNumberOfCalls(t, "TransferChat", 1)
+}
+
+func Test_Sender_Reload(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("SendEvent", ctx, mock.MatchedBy(func(p *livechat.Event) bool {
+		return p.Event.Text == "Cześć!"
+	})).Once().Return(&livechat.SendEventResponse{}, nil)
+	lcHTTP.On("ListAgentsForTransfer", ctx, mock.Anything).Return([]*livechat.ListAgentsForTransferResponse{{
+		AgentID: "abcd",
+	}}, nil)
+	lcHTTP.On("TransferChat", ctx, mock.Anything).Return(&livechat.TransferChatResponse{}, nil)
+
+	sender := helperCreateSender(t, lcHTTP)
+	transfer, _ := handoff.New(lcHTTP, &handoff.Config{Strategy: handoff.StrategyRoundRobin})
+
+	conversation, err := flow.New(&flow.Definition{
+		Initial: "start",
+		States: map[string]*flow.State{"start": {Intents: []*flow.Intent{
+			{Match: flow.Match{Type: flow.MatchExact, Values: []string{"Hello"}}, Response: flow.Response{Text: "Cześć!"}},
+			{Match: flow.Match{Type: flow.MatchExact, Values: []string{"Agent"}}, Response: flow.Response{Action: flow.ActionTransfer}},
+		}}},
+	})
+	assert.NoError(t, err)
+	sender.Reload(conversation, transfer)
+
+	handoffs := testutil.ToFloat64(metrics.Handoffs.WithLabelValues("round_robin", "transferred"))
+
+	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID, "Hello")
+	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
+
+	msg = helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID, "Agent")
+	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
+	assert.Equal(t, handoffs+1, testutil.ToFloat64(metrics.Handoffs.WithLabelValues("round_robin", "transferred")))
+}
+
+func Test_Sender_File(t *testing.T) {
+	lcHTTP := new(mocks.LivechatRequests)
+	ctx := context.Background()
+
+	lcHTTP.On("SendEvent", ctx, mock.MatchedBy(func(p *livechat.Event) bool {
+		return p.Event.Text == "Dziękuję za plik, przekażę go człowiekowi."
+	})).Once().Return(&livechat.SendEventResponse{}, nil)
+	lcHTTP.On("ListAgentsForTransfer", ctx, mock.Anything).Return([]*livechat.ListAgentsForTransferResponse{{
+		AgentID: "abcd",
+	}}, nil)
+	lcHTTP.On("TransferChat", ctx, mock.Anything).Return(&livechat.TransferChatResponse{}, nil)
+
+	sender := helperCreateSender(t, lcHTTP)
 
-	sender := NewSender(lcHTTP, definedAuthorID)
+	// system messages are ignored
+	msg := helperBuildPushIncomingEvent(t, definedLicenseID, definedChatID, "")
+	msg.Payload.Event.ChatEvent = &livechat.SystemMessageEvent{
+		EventMeta:         livechat.EventMeta{Type: livechat.EventTypeSystemMessage},
+		SystemMessageType: "routing.assigned",
+	}
 	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
+
+	msg.Payload.Event.ChatEvent = &livechat.FileEvent{
+		EventMeta:   livechat.EventMeta{Type: livechat.EventTypeFile, AuthorID: "customer_id"},
+		ContentType: "application/pdf",
+	}
+	assert.NoError(t, sender.Talk(ctx, definedChatID, msg))
+	lcHTTP.AssertNumberOfCalls(t, "SendEvent", 1)
 	lcHTTP.AssertNumberOfCalls(t, "TransferChat", 1)
 }
 
-func helperBuildPushIncomingEvent(t *testing.T, licenseID livechat.LicenseID, chatID livechat.ChatID) *livechat.PushIncomingMessage {
+func helperCreateSender(t *testing.T, lcHTTP *mocks.LivechatRequests) ReloadableSender {
+	t.Helper()
+
+	conversation, err := flow.New(flow.Default())
+	if err != nil {
+		t.Fatalf("cannot create flow: %s", err)
+	}
+	transfer, err := handoff.New(lcHTTP, &handoff.Config{})
+	if err != nil {
+		t.Fatalf("cannot create handoff: %s", err)
+	}
+	return NewSender(lcHTTP, definedAuthorID, conversation, transfer)
+}
+
+func helperBuildPushIncomingEvent(t *testing.T, licenseID livechat.LicenseID, chatID livechat.ChatID, text string) *livechat.PushIncomingMessage {
 	t.Helper()
 
-	return &livechat.PushIncomingMessage{
-		Action:    "incoming_message",
-		LicenseID: licenseID,
-		Payload: struct {
-			ChatID   livechat.ChatID "json:\"chat_id\""
-			ThreadID string          "json:\"thread_id,omitempty\""
-			Event    struct {
-				Type     string "json:\"type\""
-				Text     string "json:\"text\""
-				AuthorID string "json:\"author_id\""
-			} "json:\"event\""
-		}{
-			ChatID: chatID,
-			Event: struct {
-				Type     string "json:\"type\""
-				Text     string "json:\"text\""
-				AuthorID string "json:\"author_id\""
-			}{
-				Type: "message",
-			},
-		},
+	msg := &livechat.PushIncomingMessage{Action: "incoming_event", LicenseID: licenseID}
+	msg.Payload.ChatID = chatID
+	msg.Payload.Event.ChatEvent = &livechat.MessageEvent{
+		EventMeta: livechat.EventMeta{Type: livechat.EventTypeMessage},
+		Text:      text,
 	}
+	return msg
 }
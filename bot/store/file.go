@@ -0,0 +1,109 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+// file keeps JSON snapshot of all licenses. Every change rewrites
+// the whole snapshot (through temporary file and rename).
+type file struct {
+	path     string
+	mu       sync.Mutex
+	licenses map[livechat.LicenseID]*License
+}
+
+type fileSnapshot struct {
+	Licenses []*License `json:"licenses"`
+}
+
+func NewFile(path string) (Store, error) {
+	s := &file{
+		path:     path,
+		licenses: make(map[livechat.LicenseID]*License),
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: %w", err)
+	}
+
+	var snapshot fileSnapshot
+	if err := json.Unmarshal(content, &snapshot); err != nil {
+		return nil, fmt.Errorf("store: cannot decode %s: %w", path, err)
+	}
+	for _, license := range snapshot.Licenses {
+		s.licenses[license.ID] = license
+	}
+
+	return s, nil
+}
+
+func (s *file) Load() ([]*License, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortLicenses(s.licenses), nil
+}
+
+func (s *file) Save(license *License) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.licenses[license.ID] = license
+	return s.flush()
+}
+
+func (s *file) Delete(id livechat.LicenseID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.licenses, id)
+	return s.flush()
+}
+
+// Ping checks whether snapshot can be written next to the store file.
+func (s *file) Ping() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	tmp.Close()
+
+	return os.Remove(tmp.Name())
+}
+
+func (s *file) flush() error {
+	content, err := json.MarshalIndent(&fileSnapshot{Licenses: sortLicenses(s.licenses)}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_File_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	expiry := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	s, err := NewFile(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Save(&License{
+		ID:        livechat.LicenseID(1234),
+		SecretKey: "secret",
+		Webhooks:  map[string]string{"incoming_chat": "webhook_1"},
+		Bots:      []*Bot{{ID: "bot_1", Chats: []livechat.ChatID{"chat_1", "chat_2"}}},
+		Token:     &Token{AccessToken: "access_1", RefreshToken: "refresh_1", Expiry: expiry},
+	}))
+	assert.NoError(t, s.Save(&License{ID: livechat.LicenseID(4321)}))
+	assert.NoError(t, s.Delete(livechat.LicenseID(4321)))
+
+	restored, err := NewFile(path)
+	assert.NoError(t, err)
+
+	licenses, err := restored.Load()
+	assert.NoError(t, err)
+	assert.Len(t, licenses, 1)
+	assert.Equal(t, "secret", licenses[0].SecretKey)
+	assert.Equal(t, "webhook_1", licenses[0].Webhooks["incoming_chat"])
+	assert.Equal(t, []livechat.ChatID{"chat_1", "chat_2"}, licenses[0].Bots[0].Chats)
+	if assert.NotNil(t, licenses[0].Token) {
+		assert.Equal(t, "refresh_1", licenses[0].Token.RefreshToken)
+		assert.True(t, expiry.Equal(licenses[0].Token.Expiry))
+	}
+}
+
+func Test_File_MissingFile(t *testing.T) {
+	s, err := NewFile(filepath.Join(t.TempDir(), "state.json"))
+	assert.NoError(t, err)
+
+	licenses, err := s.Load()
+	assert.NoError(t, err)
+	assert.Len(t, licenses, 0)
+}
+
+func Test_File_InvalidContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"licenses": [`), 0600))
+
+	_, err := NewFile(path)
+	assert.Error(t, err)
+}
+
+func Test_File_Ping(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := NewFile(filepath.Join(dir, "state.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Ping())
+
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 0)
+
+	missing, err := NewFile(filepath.Join(dir, "missing", "state.json"))
+	assert.NoError(t, err)
+	assert.Error(t, missing.Ping())
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"time"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+// License is a snapshot of everything bot needs to serve
+// an installed license after restart.
+type License struct {
+	ID        livechat.LicenseID `json:"id"`
+	SecretKey string             `json:"secret_key"`
+	// Webhooks maps action name into ID of registered webhook.
+	Webhooks map[string]string `json:"webhooks"`
+	Bots     []*Bot            `json:"bots"`
+	// Token lets restored license work without being authorized again.
+	Token *Token `json:"token,omitempty"`
+}
+
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+type Bot struct {
+	ID livechat.AgentID `json:"id"`
+	// MaxChats is a number of chats served by bot at once, 0 means no limit.
+	MaxChats int               `json:"max_chats,omitempty"`
+	Chats    []livechat.ChatID `json:"chats"`
+}
+
+type Store interface {
+	// Load returns every license saved in store.
+	Load() ([]*License, error)
+	// Save replaces snapshot of license.
+	Save(*License) error
+	Delete(livechat.LicenseID) error
+	// Ping reports whether store is able to save changes.
+	Ping() error
+}
+
+// New returns file-based store or in-memory one if path is empty.
+func New(path string) (Store, error) {
+	if path == "" {
+		return NewMemory(), nil
+	}
+	return NewFile(path)
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+type memory struct {
+	mu       sync.Mutex
+	licenses map[livechat.LicenseID]*License
+}
+
+func NewMemory() Store {
+	return &memory{licenses: make(map[livechat.LicenseID]*License)}
+}
+
+func (s *memory) Load() ([]*License, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortLicenses(s.licenses), nil
+}
+
+func (s *memory) Save(license *License) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.licenses[license.ID] = license
+	return nil
+}
+
+func (s *memory) Delete(id livechat.LicenseID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.licenses, id)
+	return nil
+}
+
+func sortLicenses(licenses map[livechat.LicenseID]*License) []*License {
+	list := make([]*License, 0, len(licenses))
+	for _, license := range licenses {
+		list = append(list, license)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	return list
+}
+
+func (s *memory) Ping() error { return nil }
@@ -3,10 +3,18 @@ package main
 import (
 	"encoding/json"
 	"io"
-	"os"
+	"time"
 
 	"github.com/go-playground/validator"
+	"github.com/livechat/onboarding/bot/agents"
+	"github.com/livechat/onboarding/bot/dedup"
+	"github.com/livechat/onboarding/bot/flow"
+	"github.com/livechat/onboarding/bot/handoff"
+	"github.com/livechat/onboarding/bot/queue"
+	"github.com/livechat/onboarding/bot/routing"
 	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web"
+	log "github.com/sirupsen/logrus"
 )
 
 type appMethod string
@@ -17,10 +25,23 @@ const (
 )
 
 type config struct {
-	Methods     appMethod   `json:"methods"`
-	Auth        authConfig  `json:"auth" validate:"required"`
-	Credentials credentials `json:"credentials" validate:"required"`
-	URL         urlConfig   `json:"url" validate:"required"`
+	Methods     appMethod    `json:"methods" validate:"omitempty,oneof=webhooks rtm"`
+	Auth        authConfig   `json:"auth" validate:"required"`
+	Credentials credentials  `json:"credentials" validate:"required"`
+	URL         urlConfig    `json:"url" validate:"required"`
+	Store       storeConfig  `json:"store"`
+	Bot         botConfig    `json:"bot"`
+	API         apiConfig    `json:"api"`
+	Server      serverConfig `json:"server"`
+	Health      healthConfig `json:"health"`
+	Log         logConfig    `json:"log"`
+	Queue       queueConfig  `json:"queue"`
+	Dedup       dedupConfig  `json:"dedup"`
+	// Admin enables admin API, it is not mounted if empty.
+	Admin *adminConfig `json:"admin" validate:"omitempty"`
+
+	// path of JSON file config has been read from, watched for changes.
+	path string
 }
 
 func (c *config) SelectMethod() appMethod {
@@ -47,34 +68,219 @@ type urlConfig struct {
 	Local string `json:"local" validate:"required"`
 }
 
-func LoadConfig(reader io.Reader) (*config, error) {
-	var err error
-	var cfg *config
+type serverConfig struct {
+	// Address to listen on, ":8081" if empty.
+	Address string `json:"address"`
+	// TLSCert and TLSKey are paths of certificate and its key,
+	// server listens with plain HTTP if empty.
+	TLSCert string `json:"tls_cert" validate:"required_with=TLSKey"`
+	TLSKey  string `json:"tls_key" validate:"required_with=TLSCert"`
 
-	if err = json.NewDecoder(reader).Decode(&cfg); err != nil {
-		return cfg, err
+	ReadHeaderTimeoutMs int `json:"read_header_timeout_ms" validate:"omitempty,min=1"`
+	ReadTimeoutMs       int `json:"read_timeout_ms" validate:"omitempty,min=1"`
+	WriteTimeoutMs      int `json:"write_timeout_ms" validate:"omitempty,min=1"`
+	IdleTimeoutMs       int `json:"idle_timeout_ms" validate:"omitempty,min=1"`
+	// MaxBodyBytes limits size of request body, 1MB if empty.
+	MaxBodyBytes int64 `json:"max_body_bytes" validate:"omitempty,min=1"`
+}
+
+func (c *serverConfig) SelectAddress() string {
+	if c.Address == "" {
+		return defaultAddress
 	}
-	if err = validator.New().Struct(cfg); err != nil {
-		return cfg, err
+	return c.Address
+}
+
+func (c *serverConfig) SelectMaxBodyBytes() int64 {
+	if c.MaxBodyBytes == 0 {
+		return defaultMaxBodyBytes
 	}
+	return c.MaxBodyBytes
+}
 
-	return cfg, nil
+type healthConfig struct {
+	// Licenses which have to be installed before app reports readiness.
+	Licenses []livechat.LicenseID `json:"licenses"`
 }
 
-func LoadConfigFile(filename string) (*config, error) {
-	var err error
-	var cfg *config
+type logConfig struct {
+	// Level of logged messages, "debug" if empty.
+	Level string `json:"level" validate:"omitempty,oneof=panic fatal error warn warning info debug trace"`
+}
 
-	file, err := os.Open(filename)
+func (c *logConfig) SelectLevel() log.Level {
+	level, err := log.ParseLevel(c.Level)
 	if err != nil {
-		return cfg, err
+		return log.DebugLevel
 	}
-	defer file.Close()
+	return level
+}
 
-	cfg, err = LoadConfig(file)
-	if err != nil {
+// queueConfig limits background processing of pushes.
+type queueConfig struct {
+	// Workers per license, 4 if empty.
+	Workers int `json:"workers" validate:"omitempty,min=1"`
+	// Size of backlog of every worker, webhooks above it are rejected
+	// and redelivered later by LiveChat, RTM pushes are dropped. 64 if empty.
+	Size int `json:"size" validate:"omitempty,min=1"`
+}
+
+func (c *queueConfig) Queue() *queue.Config {
+	return &queue.Config{Workers: c.Workers, Size: c.Size}
+}
+
+// dedupConfig defines how long redelivered webhooks are recognized.
+type dedupConfig struct {
+	// Size is the number of remembered webhooks, 10000 if empty.
+	Size     int `json:"size" validate:"omitempty,min=1"`
+	WindowMs int `json:"window_ms" validate:"omitempty,min=1"`
+}
+
+func (c *dedupConfig) Store() dedup.Store {
+	return dedup.NewLRU(c.Size, time.Duration(c.WindowMs)*time.Millisecond)
+}
+
+type adminConfig struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// storeConfig is used only by webhook bots. RTM bots keep no state, so
+// after restart their licenses have to be authorized and installed again.
+type storeConfig struct {
+	// Path of file with saved state (including OAuth tokens of licenses).
+	// State is kept only in memory if empty.
+	Path string `json:"path"`
+}
+
+type botConfig struct {
+	// Flow defines conversation with customer. Default flow is used if empty.
+	Flow *flow.Definition `json:"flow"`
+	// Handoff defines how chats are transferred to humans.
+	Handoff handoffConfig `json:"handoff"`
+	// Routing rules pick bots for incoming chats, the first matching
+	// rule wins. Every bot may take every chat if empty.
+	Routing []*routing.Rule `json:"routing"`
+	// Capacity limits load of bots, they take any number of chats if empty.
+	Capacity capacityConfig `json:"capacity"`
+}
+
+type capacityConfig struct {
+	// MaxChats served by bot at once, limit of bot's account is used if empty.
+	MaxChats int `json:"max_chats" validate:"omitempty,min=1"`
+	// MinBots enabled on install, 1 if empty.
+	MinBots int `json:"min_bots" validate:"omitempty,min=1"`
+	// MaxBots limits bots added when the others are at capacity,
+	// bots are not added if empty.
+	MaxBots int `json:"max_bots" validate:"omitempty,gtefield=MinBots"`
+}
+
+func (c *capacityConfig) Capacity() *agents.Capacity {
+	return &agents.Capacity{MaxChats: c.MaxChats, MinBots: c.MinBots, MaxBots: c.MaxBots}
+}
+
+type handoffConfig struct {
+	Strategy string `json:"strategy" validate:"omitempty,oneof=least_busy round_robin group"`
+	// Groups are used by "group" strategy.
+	Groups []int `json:"groups" validate:"omitempty,dive,min=0"`
+	// QueueGroup is the group where chat waits if nobody could take it.
+	// Customer is told there is nobody to talk to if empty.
+	QueueGroup *int `json:"queue_group" validate:"omitempty,min=0"`
+}
+
+func (c *handoffConfig) Handoff() *handoff.Config {
+	return &handoff.Config{
+		Strategy:   c.Strategy,
+		Groups:     c.Groups,
+		QueueGroup: c.QueueGroup,
+	}
+}
+
+func (c *botConfig) SelectFlow() *flow.Definition {
+	if c.Flow == nil {
+		return flow.Default()
+	}
+	return c.Flow
+}
+
+type apiConfig struct {
+	Retry     retryConfig     `json:"retry"`
+	RateLimit rateLimitConfig `json:"rate_limit"`
+}
+
+type retryConfig struct {
+	// MaxAttempts includes the first attempt, 1 disables retries.
+	MaxAttempts int `json:"max_attempts" validate:"omitempty,min=1"`
+	BaseDelayMs int `json:"base_delay_ms" validate:"omitempty,min=1"`
+	MaxDelayMs  int `json:"max_delay_ms" validate:"omitempty,min=1"`
+}
+
+type rateLimitConfig struct {
+	// RequestsPerSecond per license, default is used if zero and
+	// negative value disables the limit.
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst" validate:"omitempty,min=1"`
+}
+
+// Client returns configuration of LiveChat API client, unset values
+// are taken from defaults.
+func (c *apiConfig) Client() *web.Config {
+	cfg := &web.Config{
+		Retry:     web.DefaultRetryPolicy,
+		RateLimit: web.DefaultRateLimit,
+	}
+
+	if c.Retry.MaxAttempts != 0 {
+		cfg.Retry.MaxAttempts = c.Retry.MaxAttempts
+	}
+	if c.Retry.BaseDelayMs != 0 {
+		cfg.Retry.BaseDelay = time.Duration(c.Retry.BaseDelayMs) * time.Millisecond
+	}
+	if c.Retry.MaxDelayMs != 0 {
+		cfg.Retry.MaxDelay = time.Duration(c.Retry.MaxDelayMs) * time.Millisecond
+	}
+	if c.RateLimit.RequestsPerSecond != 0 {
+		cfg.RateLimit.RequestsPerSecond = c.RateLimit.RequestsPerSecond
+	}
+	if c.RateLimit.Burst != 0 {
+		cfg.RateLimit.Burst = c.RateLimit.Burst
+	}
+
+	return cfg
+}
+
+func defaultConfig() *config {
+	return &config{
+		Methods: webhooksMethod,
+		Server:  serverConfig{Address: defaultAddress},
+	}
+}
+
+func LoadConfig(reader io.Reader) (*config, error) {
+	cfg := defaultConfig()
+
+	if err := json.NewDecoder(reader).Decode(cfg); err != nil {
 		return cfg, err
 	}
 
-	return cfg, file.Close()
+	return cfg, validateConfig(cfg)
+}
+
+func validateConfig(cfg *config) error {
+	var err error
+
+	if err = validator.New().Struct(cfg); err != nil {
+		return err
+	}
+	if _, err = flow.New(cfg.Bot.SelectFlow()); err != nil {
+		return err
+	}
+	if _, err = handoff.NewStrategy(cfg.Bot.Handoff.Strategy, cfg.Bot.Handoff.Groups); err != nil {
+		return err
+	}
+	if _, err = routing.New(cfg.Bot.Routing); err != nil {
+		return err
+	}
+
+	return nil
 }
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	envPrefix         = "ONBOARDING_"
+	defaultConfigPath = "config.json"
+)
+
+// LoadConfigSources builds configuration from sources, every one overrides
+// the previous: defaults, JSON file, environment variables and CLI flags.
+// Path of JSON file is selected with "-config" flag. The file is optional
+// unless the flag is given.
+//
+// Every scalar setting can be set with environment variable or flag named
+// after its JSON path, e.g. "credentials.client_secret" is read from
+// ONBOARDING_CREDENTIALS_CLIENT_SECRET and -credentials.client_secret.
+func LoadConfigSources(args []string, environ []string) (*config, error) {
+	cfg := defaultConfig()
+	fields := configFields(reflect.TypeOf(cfg).Elem(), nil, nil)
+
+	flags := flag.NewFlagSet("onboarding", flag.ContinueOnError)
+	path := flags.String("config", defaultConfigPath, "path of JSON configuration file")
+
+	overrides := []*fieldValue{}
+	for _, field := range fields {
+		flags.Var(&flagValue{field: field, overrides: &overrides}, field.FlagName(), field.Usage())
+	}
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	explicit := false
+	flags.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "config" })
+
+	content, err := os.ReadFile(*path)
+	switch {
+	case os.IsNotExist(err) && !explicit:
+	case err != nil:
+		return cfg, err
+	default:
+		if err := json.Unmarshal(content, cfg); err != nil {
+			return cfg, fmt.Errorf("config: cannot decode %s: %w", *path, err)
+		}
+	}
+	cfg.path = *path
+
+	env := parseEnviron(environ)
+	for _, field := range fields {
+		if value, ok := env[field.EnvName()]; ok {
+			if err := field.Set(cfg, value); err != nil {
+				return cfg, fmt.Errorf("config: %s: %w", field.EnvName(), err)
+			}
+		}
+	}
+
+	for _, override := range overrides {
+		if err := override.field.Set(cfg, override.value); err != nil {
+			return cfg, fmt.Errorf("config: -%s: %w", override.field.FlagName(), err)
+		}
+	}
+
+	return cfg, validateConfig(cfg)
+}
+
+// configField is a scalar (or list of scalars) setting of config.
+type configField struct {
+	path  []string
+	index [][]int
+	typ   reflect.Type
+}
+
+func (f *configField) FlagName() string { return strings.Join(f.path, ".") }
+func (f *configField) EnvName() string {
+	return envPrefix + strings.ToUpper(strings.Join(f.path, "_"))
+}
+func (f *configField) Usage() string {
+	return fmt.Sprintf("overrides %q (env %s)", f.FlagName(), f.EnvName())
+}
+
+// Set parses value and stores it in config, allocating
+// every nil struct on the way.
+func (f *configField) Set(cfg *config, value string) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, index := range f.index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByIndex(index)
+	}
+
+	return setValue(v, value)
+}
+
+type fieldValue struct {
+	field *configField
+	value string
+}
+
+// flagValue only collects flags, so they can be applied after file
+// and environment variables are read.
+type flagValue struct {
+	field     *configField
+	overrides *[]*fieldValue
+}
+
+func (v *flagValue) String() string { return "" }
+func (v *flagValue) Set(value string) error {
+	*v.overrides = append(*v.overrides, &fieldValue{field: v.field, value: value})
+	return nil
+}
+
+var configPkgPath = reflect.TypeOf(config{}).PkgPath()
+
+// configFields lists settings of config struct. Only structs declared
+// in this package are walked into, others (e.g. conversation flow)
+// can be set only with JSON file.
+func configFields(t reflect.Type, path []string, index [][]int) []*configField {
+	fields := []*configField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		fieldPath := append(append([]string{}, path...), name)
+		fieldIndex := append(append([][]int{}, index...), field.Index)
+
+		typ := field.Type
+		if typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct {
+			typ = typ.Elem()
+		}
+
+		switch {
+		case typ.Kind() == reflect.Struct && typ.PkgPath() == configPkgPath:
+			fields = append(fields, configFields(typ, fieldPath, fieldIndex)...)
+		case isSettable(field.Type):
+			fields = append(fields, &configField{path: fieldPath, index: fieldIndex, typ: field.Type})
+		}
+	}
+
+	return fields
+}
+
+func isSettable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return isScalar(t.Elem())
+	default:
+		return isScalar(t)
+	}
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+// setValue parses value into v. Lists are separated with commas.
+func setValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(ptr)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func parseEnviron(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+	for _, variable := range environ {
+		if !strings.HasPrefix(variable, envPrefix) {
+			continue
+		}
+		if parts := strings.SplitN(variable, "=", 2); len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LoadConfigSources_Precedence(t *testing.T) {
+	path := helperWriteConfig(t, `{
+		"auth": {"username": "u", "password": "p"},
+		"credentials": {"client_id": "c", "client_secret": "from_file", "author_id": "a"},
+		"url": {"http": "h", "ws": "w", "local": "l"},
+		"server": {"address": ":9000"}
+	}`)
+
+	cfg, err := LoadConfigSources([]string{"-config", path, "-url.local", "http://flag"}, []string{
+		"ONBOARDING_CREDENTIALS_CLIENT_SECRET=from_env",
+		"ONBOARDING_URL_LOCAL=http://env",
+		"OTHER_VARIABLE=ignored",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "from_env", cfg.Credentials.Secret)
+	assert.Equal(t, "http://flag", cfg.URL.Local)
+	assert.Equal(t, ":9000", cfg.Server.Address)
+	// defaults
+	assert.Equal(t, appMethod(webhooksMethod), cfg.Methods)
+}
+
+func Test_LoadConfigSources_Types(t *testing.T) {
+	path := helperWriteConfig(t, `{
+		"auth": {"username": "u", "password": "p"},
+		"credentials": {"client_id": "c", "client_secret": "s", "author_id": "a"},
+		"url": {"http": "h", "ws": "w", "local": "l"}
+	}`)
+
+	cfg, err := LoadConfigSources([]string{
+		"-config", path,
+		"-health.licenses", "1234, 4321",
+		"-bot.handoff.queue_group", "2",
+		"-api.rate_limit.requests_per_second", "2.5",
+	}, []string{
+		"ONBOARDING_ADMIN_USERNAME=admin",
+		"ONBOARDING_ADMIN_PASSWORD=secret",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []livechat.LicenseID{1234, 4321}, cfg.Health.Licenses)
+	if assert.NotNil(t, cfg.Bot.Handoff.QueueGroup) {
+		assert.Equal(t, 2, *cfg.Bot.Handoff.QueueGroup)
+	}
+	assert.Equal(t, 2.5, cfg.API.RateLimit.RequestsPerSecond)
+	if assert.NotNil(t, cfg.Admin) {
+		assert.Equal(t, "secret", cfg.Admin.Password)
+	}
+}
+
+func Test_LoadConfigSources_Invalid(t *testing.T) {
+	path := helperWriteConfig(t, `{
+		"auth": {"username": "u", "password": "p"},
+		"credentials": {"client_id": "c", "client_secret": "s", "author_id": "a"},
+		"url": {"http": "h", "ws": "w", "local": "l"}
+	}`)
+
+	tests := map[string]struct {
+		args []string
+		env  []string
+	}{
+		"missing file":      {[]string{"-config", filepath.Join(t.TempDir(), "missing.json")}, nil},
+		"unknown flag":      {[]string{"-config", path, "-random"}, nil},
+		"invalid number":    {[]string{"-config", path}, []string{"ONBOARDING_SERVER_MAX_BODY_BYTES=many"}},
+		"failed validation": {[]string{"-config", path, "-methods", "email"}, nil},
+		"missing password":  {[]string{"-config", path}, []string{"ONBOARDING_ADMIN_USERNAME=admin"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadConfigSources(tt.args, tt.env)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_LoadConfigSources_EnvOnly(t *testing.T) {
+	environ := []string{
+		"ONBOARDING_AUTH_USERNAME=u", "ONBOARDING_AUTH_PASSWORD=p",
+		"ONBOARDING_CREDENTIALS_CLIENT_ID=c", "ONBOARDING_CREDENTIALS_CLIENT_SECRET=s", "ONBOARDING_CREDENTIALS_AUTHOR_ID=a",
+		"ONBOARDING_URL_HTTP=h", "ONBOARDING_URL_WS=w", "ONBOARDING_URL_LOCAL=l",
+	}
+
+	path := helperWriteConfig(t, `{}`)
+	_, err := LoadConfigSources([]string{"-config", path}, environ)
+	assert.NoError(t, err)
+
+	// file at the default path is optional
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("cannot change directory: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	cfg, err := LoadConfigSources(nil, environ)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultConfigPath, cfg.path)
+}
+
+func helperWriteConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("cannot write config: %s", err)
+	}
+	return path
+}
@@ -2,20 +2,19 @@ package main
 
 import (
 	"bytes"
+	"os"
 	"testing"
 )
 
 func Test_LoadConfig_Valid(t *testing.T) {
-	_, err := LoadConfigFile("./config.dist.json")
+	file, err := os.Open("./config.dist.json")
 	if err != nil {
-		t.Fatalf("LoadConfigFile returns non-empty err: %s", err)
+		t.Fatalf("cannot open config: %s", err)
 	}
-}
+	defer file.Close()
 
-func Test_LoadConfig_Invalid(t *testing.T) {
-	_, err := LoadConfigFile("./config.never.json")
-	if err == nil {
-		t.Fatalf("LoadConfigFile returns empty err")
+	if _, err := LoadConfig(file); err != nil {
+		t.Fatalf("LoadConfig returns non-empty err: %s", err)
 	}
 }
 
@@ -26,3 +25,67 @@ func Test_LoadConfig_InvalidContent(t *testing.T) {
 		t.Fatalf("LoadConfig returns empty err")
 	}
 }
+
+func Test_Config_SelectMethod(t *testing.T) {
+	cfg := &config{}
+	if cfg.SelectMethod() != webhooksMethod {
+		t.Fatalf("SelectMethod returns %s instead of default method", cfg.SelectMethod())
+	}
+
+	cfg.Methods = rtmMethod
+	if cfg.SelectMethod() != rtmMethod {
+		t.Fatalf("SelectMethod returns %s instead of %s", cfg.SelectMethod(), rtmMethod)
+	}
+}
+
+func Test_LoadConfig_InvalidMethod(t *testing.T) {
+	content := bytes.NewReader([]byte(`{
+		"methods": "carrier_pigeon",
+		"auth": {"username": "u", "password": "p"},
+		"credentials": {"client_id": "c", "client_secret": "s", "author_id": "a"},
+		"url": {"http": "h", "ws": "w", "local": "l"}
+	}`))
+	_, err := LoadConfig(content)
+	if err == nil {
+		t.Fatalf("LoadConfig returns empty err")
+	}
+}
+
+func Test_LoadConfig_InvalidFlow(t *testing.T) {
+	content := bytes.NewReader([]byte(`{
+		"auth": {"username": "u", "password": "p"},
+		"credentials": {"client_id": "c", "client_secret": "s", "author_id": "a"},
+		"url": {"http": "h", "ws": "w", "local": "l"},
+		"bot": {"flow": {"initial": "start", "states": {"other": {"intents": []}}}}
+	}`))
+	_, err := LoadConfig(content)
+	if err == nil {
+		t.Fatalf("LoadConfig returns empty err")
+	}
+}
+
+func Test_LoadConfig_GroupHandoffWithoutGroups(t *testing.T) {
+	content := bytes.NewReader([]byte(`{
+		"auth": {"username": "u", "password": "p"},
+		"credentials": {"client_id": "c", "client_secret": "s", "author_id": "a"},
+		"url": {"http": "h", "ws": "w", "local": "l"},
+		"bot": {"handoff": {"strategy": "group"}}
+	}`))
+	_, err := LoadConfig(content)
+	if err == nil {
+		t.Fatalf("LoadConfig returns empty err")
+	}
+}
+
+func Test_LoadConfig_TLSWithoutKey(t *testing.T) {
+	content := bytes.NewReader([]byte(`{
+		"auth": {"username": "u", "password": "p"},
+		"credentials": {"client_id": "c", "client_secret": "s", "author_id": "a"},
+		"url": {"http": "h", "ws": "w", "local": "l"},
+		"server": {"tls_cert": "cert.pem"}
+	}`))
+	_, err := LoadConfig(content)
+	if err == nil {
+		t.Fatalf("LoadConfig returns empty err")
+	}
+}
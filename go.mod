@@ -6,8 +6,12 @@ require (
 	github.com/go-chi/chi/v5 v5.0.2
 	github.com/go-playground/universal-translator v0.17.0 // indirect
 	github.com/go-playground/validator v9.31.0+incompatible
+	github.com/gorilla/websocket v1.4.2
 	github.com/leodido/go-urn v1.2.1 // indirect
+	github.com/prometheus/client_golang v1.11.0
+	github.com/prometheus/client_model v0.2.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.6.1
+	golang.org/x/time v0.0.0-20210723032227-1f47c861a9ac
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 )
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/livechat"
+)
+
+const (
+	statusOK       = "ok"
+	statusFail     = "fail"
+	statusReady    = "ready"
+	statusNotReady = "not_ready"
+
+	checkTimeout = 2 * time.Second
+)
+
+type checkFunc func(context.Context) error
+
+type checkStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type readinessStatus struct {
+	Status string                  `json:"status"`
+	Checks map[string]*checkStatus `json:"checks"`
+}
+
+// readiness keeps checks of every subsystem app depends on.
+// App is ready only if all of them pass.
+type readiness struct {
+	mu     sync.Mutex
+	checks map[string]checkFunc
+}
+
+func newReadiness() *readiness {
+	return &readiness{checks: make(map[string]checkFunc)}
+}
+
+func (r *readiness) Add(name string, check checkFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = check
+}
+
+// Check runs every check concurrently.
+func (r *readiness) Check(ctx context.Context) *readinessStatus {
+	r.mu.Lock()
+	checks := make(map[string]checkFunc, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	status := &readinessStatus{Status: statusReady, Checks: make(map[string]*checkStatus, len(checks))}
+
+	for name, check := range checks {
+		wg.Add(1)
+
+		go func(name string, check checkFunc) {
+			defer wg.Done()
+
+			result := &checkStatus{Status: statusOK}
+			if err := check(ctx); err != nil {
+				result.Status = statusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			status.Checks[name] = result
+			if result.Status != statusOK {
+				status.Status = statusNotReady
+			}
+		}(name, check)
+	}
+
+	wg.Wait()
+	return status
+}
+
+func (r *readiness) Handler(w http.ResponseWriter, req *http.Request) {
+	status := r.Check(req.Context())
+	if status.Status != statusReady {
+		sendJSON(w, http.StatusServiceUnavailable, status)
+		return
+	}
+	sendJSON(w, http.StatusOK, status)
+}
+
+// handleHealthz reports only that process is able to serve requests.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, http.StatusOK, map[string]string{"status": statusOK})
+}
+
+// checkToken passes once any license (and every required one) has been authorized.
+func checkToken(admin bot.Admin, required []livechat.LicenseID) checkFunc {
+	return func(context.Context) error {
+		authorized := admin.Authorized()
+		if len(authorized) == 0 {
+			return fmt.Errorf("no license has been authorized")
+		}
+
+	out:
+		for _, id := range required {
+			for _, authorizedID := range authorized {
+				if id == authorizedID {
+					continue out
+				}
+			}
+			return fmt.Errorf("license %v has not been authorized", id)
+		}
+		return nil
+	}
+}
+
+// checkLicenses passes once every required license has been installed.
+func checkLicenses(admin bot.Admin, required []livechat.LicenseID) checkFunc {
+	return func(context.Context) error {
+		for _, id := range required {
+			if _, err := admin.License(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// checkLivechatAPI passes if LiveChat API responds at all,
+// the status of response does not matter.
+func checkLivechatAPI(client livechat.Client, url string) checkFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+}
+
+type pinger interface {
+	Ping() error
+}
+
+func checkPing(p pinger) checkFunc {
+	return func(context.Context) error {
+		return p.Ping()
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+type healthAdmin struct {
+	bot.Admin
+	authorized []livechat.LicenseID
+	installed  []livechat.LicenseID
+}
+
+func (a *healthAdmin) Authorized() []livechat.LicenseID { return a.authorized }
+
+func (a *healthAdmin) License(id livechat.LicenseID) (*bot.LicenseInfo, error) {
+	for _, installedID := range a.installed {
+		if installedID == id {
+			return &bot.LicenseInfo{ID: id}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w (license id: %v)", bot.ErrLicenseNotFound, id)
+}
+
+func Test_Readiness(t *testing.T) {
+	required := []livechat.LicenseID{1234}
+
+	tests := map[string]struct {
+		admin  *healthAdmin
+		status int
+		failed []string
+	}{
+		"not authorized":      {&healthAdmin{}, http.StatusServiceUnavailable, []string{"token", "licenses"}},
+		"other license":       {&healthAdmin{authorized: []livechat.LicenseID{4321}}, http.StatusServiceUnavailable, []string{"token", "licenses"}},
+		"authorized only":     {&healthAdmin{authorized: required}, http.StatusServiceUnavailable, []string{"licenses"}},
+		"authorized, install": {&healthAdmin{authorized: required, installed: required}, http.StatusOK, nil},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			checks := newReadiness()
+			checks.Add("token", checkToken(tt.admin, required))
+			checks.Add("licenses", checkLicenses(tt.admin, required))
+
+			w := httptest.NewRecorder()
+			checks.Handler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, tt.status, w.Code)
+
+			var status readinessStatus
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+			for _, name := range tt.failed {
+				assert.Equal(t, statusFail, status.Checks[name].Status, name)
+			}
+		})
+	}
+}
+
+func Test_Readiness_FailedCheck(t *testing.T) {
+	checks := newReadiness()
+	checks.Add("store", func(context.Context) error { return errors.New("read-only file system") })
+	checks.Add("livechat_api", func(context.Context) error { return nil })
+
+	status := checks.Check(context.Background())
+	assert.Equal(t, statusNotReady, status.Status)
+	assert.Equal(t, "read-only file system", status.Checks["store"].Error)
+	assert.Equal(t, statusOK, status.Checks["livechat_api"].Status)
+}
+
+func Test_CheckLivechatAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	check := checkLivechatAPI(server.Client(), server.URL)
+	assert.NoError(t, check(context.Background()))
+
+	server.Close()
+	assert.Error(t, check(context.Background()))
+}
+
+func Test_Healthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	handleHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
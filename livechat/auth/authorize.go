@@ -13,6 +13,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const tokenURL = "https://accounts.livechat.com/v2/token"
+
 type AuthorizeCredentials struct {
 	Code        string
 	ClientID    livechat.ClientID
@@ -21,9 +23,11 @@ type AuthorizeCredentials struct {
 }
 
 type AuthorizationResponse struct {
-	AccessToken  string `json:"access_token"`
-	AccountID    string `json:"account_id"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string             `json:"access_token"`
+	AccountID    string             `json:"account_id"`
+	LicenseID    livechat.LicenseID `json:"license_id"`
+	RefreshToken string             `json:"refresh_token"`
+	ExpiresIn    int                `json:"expires_in"`
 }
 
 type authErrorMessage struct {
@@ -39,7 +43,23 @@ func Authorize(ctx context.Context, client livechat.Client, data *AuthorizeCrede
 	b.Set("client_secret", data.Secret)
 	b.Set("redirect_uri", data.RedirectURI)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://accounts.livechat.com/v2/token", strings.NewReader(b.Encode()))
+	return requestToken(ctx, client, b)
+}
+
+// Refresh exchanges refresh token for a new access token
+// (grant type "refresh_token").
+func Refresh(ctx context.Context, client livechat.Client, data *AuthorizeCredentials, refreshToken string) (*AuthorizationResponse, error) {
+	b := url.Values{}
+	b.Set("grant_type", "refresh_token")
+	b.Set("refresh_token", refreshToken)
+	b.Set("client_id", string(data.ClientID))
+	b.Set("client_secret", data.Secret)
+
+	return requestToken(ctx, client, b)
+}
+
+func requestToken(ctx context.Context, client livechat.Client, b url.Values) (*AuthorizationResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(b.Encode()))
 	if err != nil {
 		return &AuthorizationResponse{}, fmt.Errorf("auth: %w", err)
 	}
@@ -61,6 +81,7 @@ func Authorize(ctx context.Context, client livechat.Client, data *AuthorizeCrede
 		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"status_code": res.StatusCode,
 			"error_type":  body.Error,
+			"grant_type":  b.Get("grant_type"),
 		}).Error(body.Desc)
 
 		return &AuthorizationResponse{}, fmt.Errorf("auth: expected status %d, got %d", http.StatusOK, res.StatusCode)
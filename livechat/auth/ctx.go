@@ -14,6 +14,7 @@ var (
 	authToken   = ctxToken("auth_bearer")
 	clientToken = ctxToken("client_id")
 	authorToken = ctxToken("with_author_token")
+	licenseKey  = ctxToken("license_id")
 )
 
 func WithPAT(ctx context.Context, username, password string) context.Context {
@@ -67,3 +68,18 @@ func GetAuthorID(ctx context.Context) (livechat.AgentID, error) {
 	}
 	return authorID, nil
 }
+
+func WithLicenseID(ctx context.Context, licenseID livechat.LicenseID) context.Context {
+	return context.WithValue(ctx, licenseKey, licenseID)
+}
+
+func GetLicenseID(ctx context.Context) (livechat.LicenseID, error) {
+	licenseID, ok := ctx.Value(licenseKey).(livechat.LicenseID)
+	if !ok {
+		return 0, fmt.Errorf("auth: missing license id")
+	}
+	if licenseID == 0 {
+		return 0, fmt.Errorf("auth: missing license id")
+	}
+	return licenseID, nil
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/livechat/onboarding/livechat"
+)
+
+// Registry keeps token sources of every authorized license.
+type Registry struct {
+	mu      sync.Mutex
+	sources map[livechat.LicenseID]*TokenSource
+	ready   map[livechat.LicenseID]chan struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		sources: make(map[livechat.LicenseID]*TokenSource),
+		ready:   make(map[livechat.LicenseID]chan struct{}),
+	}
+}
+
+// Authorize obtains token for license (read from the response)
+// and stores it in registry.
+func (r *Registry) Authorize(ctx context.Context, client livechat.Client, data *AuthorizeCredentials) (livechat.LicenseID, error) {
+	response, err := Authorize(ctx, client, data)
+	if err != nil {
+		return 0, err
+	}
+	if response.LicenseID == 0 {
+		return 0, fmt.Errorf("auth: token is not bound to any license")
+	}
+
+	r.Set(response.LicenseID, client, data, response)
+	return response.LicenseID, nil
+}
+
+// Set stores token for license. Existing token source is updated in place,
+// so everyone who already holds it gets the new token.
+func (r *Registry) Set(id livechat.LicenseID, client livechat.Client, data *AuthorizeCredentials, response *AuthorizationResponse) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if source, ok := r.sources[id]; ok {
+		source.Reset(client, data, response)
+		return
+	}
+
+	source := NewTokenSource(client, data, response)
+	source.licenseID = id
+	r.add(id, source)
+}
+
+// Restore stores token saved before restart, so license does not have to
+// be authorized again as long as the token is valid or can be refreshed.
+// Nothing is changed if license has been authorized in the meantime.
+func (r *Registry) Restore(id livechat.LicenseID, client livechat.Client, data *AuthorizeCredentials, token Token) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sources[id]; ok {
+		return
+	}
+
+	r.add(id, &TokenSource{
+		client:      client,
+		credentials: data,
+		licenseID:   id,
+		token:       &token,
+	})
+}
+
+func (r *Registry) add(id livechat.LicenseID, source *TokenSource) {
+	r.sources[id] = source
+	if ready, ok := r.ready[id]; ok {
+		close(ready)
+		delete(r.ready, id)
+	}
+}
+
+func (r *Registry) Get(id livechat.LicenseID) (*TokenSource, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	source, ok := r.sources[id]
+	if !ok {
+		return nil, fmt.Errorf("auth: license %v is not authorized", id)
+	}
+	return source, nil
+}
+
+// Wait blocks until license gets authorized or context is done.
+func (r *Registry) Wait(ctx context.Context, id livechat.LicenseID) (*TokenSource, error) {
+	r.mu.Lock()
+	if source, ok := r.sources[id]; ok {
+		r.mu.Unlock()
+		return source, nil
+	}
+
+	ready, ok := r.ready[id]
+	if !ok {
+		ready = make(chan struct{})
+		r.ready[id] = ready
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-ready:
+		return r.Get(id)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Licenses returns (sorted) IDs of authorized licenses.
+func (r *Registry) Licenses() []livechat.LicenseID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]livechat.LicenseID, 0, len(r.sources))
+	for id := range r.sources {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (r *Registry) Delete(id livechat.LicenseID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sources, id)
+}
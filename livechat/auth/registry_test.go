@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Registry_Wait(t *testing.T) {
+	registry := NewRegistry()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		registry.Set(livechat.LicenseID(1234), nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_1"})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	source, err := registry.Wait(ctx, livechat.LicenseID(1234))
+	assert.NoError(t, err)
+
+	token, _ := source.Token(ctx)
+	assert.Equal(t, "access_1", token.AccessToken)
+}
+
+func Test_Registry_Wait_Timeout(t *testing.T) {
+	registry := NewRegistry()
+	registry.Set(livechat.LicenseID(4321), nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_1"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := registry.Wait(ctx, livechat.LicenseID(1234))
+	assert.Error(t, err)
+}
+
+func Test_Registry_Set_UpdatesExistingSource(t *testing.T) {
+	registry := NewRegistry()
+	registry.Set(livechat.LicenseID(1234), nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_1"})
+
+	source, _ := registry.Get(livechat.LicenseID(1234))
+	registry.Set(livechat.LicenseID(1234), nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_2"})
+
+	token, _ := source.Token(context.Background())
+	assert.Equal(t, "access_2", token.AccessToken)
+}
+
+func Test_Registry_Licenses(t *testing.T) {
+	registry := NewRegistry()
+	assert.Empty(t, registry.Licenses())
+
+	registry.Set(livechat.LicenseID(4321), nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_1"})
+	registry.Set(livechat.LicenseID(1234), nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_2"})
+	assert.Equal(t, []livechat.LicenseID{1234, 4321}, registry.Licenses())
+
+	registry.Delete(livechat.LicenseID(4321))
+	assert.Equal(t, []livechat.LicenseID{1234}, registry.Licenses())
+}
+
+func Test_Registry_Restore(t *testing.T) {
+	registry := NewRegistry()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	expiry := time.Now().Add(time.Hour)
+	registry.Restore(livechat.LicenseID(1234), nil, &AuthorizeCredentials{}, Token{AccessToken: "access_1", RefreshToken: "refresh_1", Expiry: expiry})
+
+	source, err := registry.Wait(ctx, livechat.LicenseID(1234))
+	assert.NoError(t, err)
+	assert.Equal(t, Token{AccessToken: "access_1", RefreshToken: "refresh_1", Expiry: expiry}, source.Current())
+
+	ctx, err = source.WithOAuth(ctx)
+	assert.NoError(t, err)
+	licenseID, _ := GetLicenseID(ctx)
+	assert.Equal(t, livechat.LicenseID(1234), licenseID)
+
+	// token of license authorized again is not overwritten
+	registry.Set(livechat.LicenseID(1234), nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_2"})
+	registry.Restore(livechat.LicenseID(1234), nil, &AuthorizeCredentials{}, Token{AccessToken: "access_1"})
+	assert.Equal(t, "access_2", source.Current().AccessToken)
+}
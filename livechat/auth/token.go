@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/sirupsen/logrus"
+)
+
+// RefreshBefore defines how long before expiry the access token
+// is considered stale and gets refreshed.
+var RefreshBefore = 5 * time.Minute
+
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// Expired reports whether token expires within given leeway.
+// Token without expiry never expires.
+func (t *Token) Expired(leeway time.Duration) bool {
+	if t.Expiry.IsZero() {
+		return false
+	}
+	return time.Now().Add(leeway).After(t.Expiry)
+}
+
+// TokenSource keeps OAuth token obtained with Authorize and refreshes
+// it with "refresh_token" grant when it is about to expire.
+type TokenSource struct {
+	// mu guards every field, all of them are replaced by Reset.
+	mu          sync.Mutex
+	client      livechat.Client
+	credentials *AuthorizeCredentials
+	licenseID   livechat.LicenseID
+	token       *Token
+	onRefresh   func()
+}
+
+func NewTokenSource(client livechat.Client, credentials *AuthorizeCredentials, response *AuthorizationResponse) *TokenSource {
+	return &TokenSource{
+		client:      client,
+		credentials: credentials,
+		licenseID:   response.LicenseID,
+		token:       newToken(response),
+	}
+}
+
+// Token returns valid token, refreshing it first if needed.
+func (s *TokenSource) Token(ctx context.Context) (*Token, error) {
+	token, _, err := s.licenseToken(ctx)
+	return token, err
+}
+
+// licenseToken returns valid token together with license it belongs to.
+func (s *TokenSource) licenseToken(ctx context.Context) (*Token, livechat.LicenseID, error) {
+	s.mu.Lock()
+	token, refreshed, err := s.refresh(ctx)
+	licenseID, onRefresh := s.licenseID, s.onRefresh
+	s.mu.Unlock()
+
+	if refreshed && onRefresh != nil {
+		onRefresh()
+	}
+	return token, licenseID, err
+}
+
+// refresh returns valid token and reports whether it has been refreshed.
+func (s *TokenSource) refresh(ctx context.Context) (*Token, bool, error) {
+	if !s.token.Expired(RefreshBefore) {
+		return s.token, false, nil
+	}
+	if s.token.RefreshToken == "" {
+		if s.token.Expired(0) {
+			return nil, false, fmt.Errorf("auth: token expired at %s and cannot be refreshed", s.token.Expiry)
+		}
+		return s.token, false, nil
+	}
+
+	response, err := Refresh(ctx, s.client, s.credentials, s.token.RefreshToken)
+	if err != nil {
+		if !s.token.Expired(0) {
+			logrus.WithContext(ctx).WithError(err).Warn("Cannot refresh token, using the current one")
+			return s.token, false, nil
+		}
+		return nil, false, fmt.Errorf("auth: cannot refresh token: %w", err)
+	}
+
+	token := newToken(response)
+	if token.RefreshToken == "" {
+		token.RefreshToken = s.token.RefreshToken
+	}
+	s.token = token
+
+	logrus.WithContext(ctx).WithField("expiry", token.Expiry).Debug("Token refreshed")
+	return s.token, true, nil
+}
+
+// Current returns copy of the stored token without refreshing it.
+func (s *TokenSource) Current() Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return *s.token
+}
+
+// OnRefresh sets function called every time the token gets refreshed
+// or reset, e.g. to save the new one.
+func (s *TokenSource) OnRefresh(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onRefresh = fn
+}
+
+// Reset replaces stored token with a new one obtained by Authorize.
+func (s *TokenSource) Reset(client livechat.Client, credentials *AuthorizeCredentials, response *AuthorizationResponse) {
+	s.mu.Lock()
+	s.client = client
+	s.credentials = credentials
+	if response.LicenseID != 0 {
+		s.licenseID = response.LicenseID
+	}
+	s.token = newToken(response)
+	onRefresh := s.onRefresh
+	s.mu.Unlock()
+
+	if onRefresh != nil {
+		onRefresh()
+	}
+}
+
+// WithOAuth returns context authorized with valid access token
+// (and license which the token belongs to).
+func (s *TokenSource) WithOAuth(ctx context.Context) (context.Context, error) {
+	token, licenseID, err := s.licenseToken(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	if licenseID != 0 {
+		ctx = WithLicenseID(ctx, licenseID)
+	}
+
+	return WithOAuth(ctx, token.AccessToken), nil
+}
+
+func newToken(response *AuthorizationResponse) *Token {
+	token := &Token{
+		AccessToken:  response.AccessToken,
+		RefreshToken: response.RefreshToken,
+	}
+	if response.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return token
+}
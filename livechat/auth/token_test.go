@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_TokenSource_Valid(t *testing.T) {
+	httpClient := new(mocks.Client)
+
+	tokens := NewTokenSource(httpClient, &AuthorizeCredentials{}, &AuthorizationResponse{
+		AccessToken:  "access_1",
+		RefreshToken: "refresh_1",
+		ExpiresIn:    3600,
+		LicenseID:    livechat.LicenseID(1234),
+	})
+
+	ctx, err := tokens.WithOAuth(context.Background())
+	assert.NoError(t, err)
+
+	token, _ := GetAuthToken(ctx)
+	assert.Equal(t, "Bearer access_1", token)
+	licenseID, _ := GetLicenseID(ctx)
+	assert.Equal(t, livechat.LicenseID(1234), licenseID)
+	httpClient.AssertNumberOfCalls(t, "Do", 0)
+}
+
+func Test_TokenSource_Reset_Concurrent(t *testing.T) {
+	tokens := NewTokenSource(nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_1", LicenseID: 1234})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tokens.Reset(nil, &AuthorizeCredentials{}, &AuthorizationResponse{AccessToken: "access_2", LicenseID: 1234})
+	}()
+
+	ctx, err := tokens.WithOAuth(context.Background())
+	<-done
+	assert.NoError(t, err)
+	licenseID, _ := GetLicenseID(ctx)
+	assert.Equal(t, livechat.LicenseID(1234), licenseID)
+}
+
+func Test_TokenSource_Refresh(t *testing.T) {
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.MatchedBy(func(r *http.Request) bool {
+		r.ParseForm()
+		return r.PostForm.Get("grant_type") == "refresh_token" && r.PostForm.Get("refresh_token") == "refresh_1"
+	})).Once().Return(&http.Response{
+		Body:       io.NopCloser(strings.NewReader(`{"access_token":"access_2","expires_in":3600}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	tokens := NewTokenSource(httpClient, &AuthorizeCredentials{}, &AuthorizationResponse{
+		AccessToken:  "access_1",
+		RefreshToken: "refresh_1",
+		ExpiresIn:    60,
+	})
+	refreshed := 0
+	tokens.OnRefresh(func() {
+		refreshed++
+		assert.Equal(t, "access_2", tokens.Current().AccessToken)
+	})
+
+	token, err := tokens.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access_2", token.AccessToken)
+	assert.Equal(t, "refresh_1", token.RefreshToken)
+	assert.False(t, token.Expired(RefreshBefore))
+
+	_, err = tokens.Token(context.Background())
+	assert.NoError(t, err)
+	httpClient.AssertNumberOfCalls(t, "Do", 1)
+	assert.Equal(t, 1, refreshed)
+}
+
+func Test_TokenSource_Refresh_Failed(t *testing.T) {
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	t.Run("token is still valid", func(t *testing.T) {
+		tokens := NewTokenSource(httpClient, &AuthorizeCredentials{}, &AuthorizationResponse{
+			AccessToken:  "access_1",
+			RefreshToken: "refresh_1",
+			ExpiresIn:    60,
+		})
+
+		token, err := tokens.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "access_1", token.AccessToken)
+	})
+
+	t.Run("token has expired", func(t *testing.T) {
+		tokens := NewTokenSource(httpClient, &AuthorizeCredentials{}, &AuthorizationResponse{
+			AccessToken:  "access_1",
+			RefreshToken: "refresh_1",
+		})
+		tokens.token.Expiry = time.Now().Add(-time.Minute)
+
+		_, err := tokens.Token(context.Background())
+		assert.Error(t, err)
+	})
+}
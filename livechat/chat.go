@@ -0,0 +1,180 @@
+package livechat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	UserTypeCustomer = "customer"
+	UserTypeAgent    = "agent"
+
+	FormTypePrechat = "prechat"
+)
+
+// Chat is a chat as sent in pushes, with the thread which
+// the push is about.
+type Chat struct {
+	ID         ChatID     `json:"id"`
+	Users      []*User    `json:"users"`
+	Thread     *Thread    `json:"thread,omitempty"`
+	Access     *Access    `json:"access,omitempty"`
+	Properties Properties `json:"properties,omitempty"`
+	IsFollowed bool       `json:"is_followed,omitempty"`
+}
+
+// Customer returns the customer of chat, nil if chat has none.
+func (c *Chat) Customer() *User {
+	for _, user := range c.Users {
+		if user.Type == UserTypeCustomer {
+			return user
+		}
+	}
+	return nil
+}
+
+// GroupIDs returns groups chat belongs to.
+func (c *Chat) GroupIDs() []int {
+	if c.Access == nil {
+		return nil
+	}
+	return c.Access.GroupIDs
+}
+
+type Access struct {
+	GroupIDs []int `json:"group_ids"`
+}
+
+// Properties are grouped by namespace, e.g. "routing" => "pinned" => true.
+type Properties map[string]map[string]interface{}
+
+// Get returns property named "namespace.name" formatted as text.
+func (p Properties) Get(name string) (string, bool) {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	value, ok := p[parts[0]][parts[1]]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+type User struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"`
+	Name           string          `json:"name,omitempty"`
+	Email          string          `json:"email,omitempty"`
+	Avatar         string          `json:"avatar,omitempty"`
+	Present        bool            `json:"present"`
+	EventsSeenUpTo string          `json:"events_seen_up_to,omitempty"`
+	Visibility     string          `json:"visibility,omitempty"`
+	LastVisit      *Visit          `json:"last_visit,omitempty"`
+	Statistics     *UserStatistics `json:"statistics,omitempty"`
+	// SessionFields are set by the website with customer's tracking code.
+	SessionFields []map[string]string `json:"session_fields,omitempty"`
+}
+
+// SessionField returns value of customer's session field.
+func (u *User) SessionField(key string) (string, bool) {
+	for _, fields := range u.SessionFields {
+		if value, ok := fields[key]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+type Visit struct {
+	StartedAt   string `json:"started_at,omitempty"`
+	Referrer    string `json:"referrer,omitempty"`
+	IP          string `json:"ip,omitempty"`
+	UserAgent   string `json:"user_agent,omitempty"`
+	Geolocation *struct {
+		Country     string `json:"country,omitempty"`
+		CountryCode string `json:"country_code,omitempty"`
+		Region      string `json:"region,omitempty"`
+		City        string `json:"city,omitempty"`
+		Timezone    string `json:"timezone,omitempty"`
+	} `json:"geolocation,omitempty"`
+	LastPages []struct {
+		OpenedAt string `json:"opened_at"`
+		URL      string `json:"url"`
+		Title    string `json:"title"`
+	} `json:"last_pages,omitempty"`
+}
+
+type UserStatistics struct {
+	ChatsCount   int `json:"chats_count"`
+	ThreadsCount int `json:"threads_count"`
+	VisitsCount  int `json:"visits_count"`
+}
+
+type Thread struct {
+	ID                 string     `json:"id"`
+	Active             bool       `json:"active"`
+	UserIDs            []string   `json:"user_ids"`
+	Events             Events     `json:"events"`
+	Properties         Properties `json:"properties,omitempty"`
+	Access             *Access    `json:"access,omitempty"`
+	Tags               []string   `json:"tags,omitempty"`
+	CreatedAt          string     `json:"created_at"`
+	PreviousThreadID   string     `json:"previous_thread_id,omitempty"`
+	NextThreadID       string     `json:"next_thread_id,omitempty"`
+	PreviousAccessible bool       `json:"previous_accessible,omitempty"`
+}
+
+// Survey returns answers of pre-chat survey by label of the question.
+func (t *Thread) Survey() map[string]string {
+	answers := map[string]string{}
+	for _, event := range t.Events {
+		form, ok := event.(*FilledFormEvent)
+		if !ok || form.FormType != FormTypePrechat {
+			continue
+		}
+		for label, answer := range form.Answers() {
+			answers[label] = answer
+		}
+	}
+	return answers
+}
+
+type FormField struct {
+	ID    string `json:"id,omitempty"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	// Answer is a text, a chosen option or a list of them,
+	// depending on the type of the field.
+	Answer json.RawMessage `json:"answer,omitempty"`
+}
+
+// Text returns answer as text, chosen options are joined with commas.
+func (f *FormField) Text() string {
+	var text string
+	if err := json.Unmarshal(f.Answer, &text); err == nil {
+		return text
+	}
+
+	var option struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal(f.Answer, &option); err == nil {
+		return option.Label
+	}
+
+	var options []struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal(f.Answer, &options); err == nil {
+		labels := make([]string, 0, len(options))
+		for _, option := range options {
+			labels = append(labels, option.Label)
+		}
+		return strings.Join(labels, ", ")
+	}
+
+	return ""
+}
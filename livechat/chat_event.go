@@ -0,0 +1,184 @@
+package livechat
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	EventTypeFile          EventType = "file"
+	EventTypeFilledForm    EventType = "filled_form"
+	EventTypeSystemMessage EventType = "system_message"
+	EventTypeCustom        EventType = "custom"
+)
+
+// ChatEvent is an event of the thread, one of *MessageEvent,
+// *RichMessageEvent, *FileEvent, *FilledFormEvent, *SystemMessageEvent,
+// *CustomEvent or *UnknownEvent for types which are not modeled.
+type ChatEvent interface {
+	// Meta returns fields shared by events of every type.
+	Meta() *EventMeta
+}
+
+type EventMeta struct {
+	ID         string     `json:"id,omitempty"`
+	CustomID   string     `json:"custom_id,omitempty"`
+	Type       EventType  `json:"type"`
+	AuthorID   string     `json:"author_id,omitempty"`
+	CreatedAt  string     `json:"created_at,omitempty"`
+	Visibility string     `json:"visibility,omitempty"`
+	Properties Properties `json:"properties,omitempty"`
+}
+
+func (e *EventMeta) Meta() *EventMeta { return e }
+
+type MessageEvent struct {
+	EventMeta
+	Text string `json:"text"`
+	// Postback is set if the message has been sent with rich message button.
+	Postback *struct {
+		ID       string `json:"id"`
+		ThreadID string `json:"thread_id"`
+		EventID  string `json:"event_id"`
+		Type     string `json:"type,omitempty"`
+		Value    string `json:"value,omitempty"`
+	} `json:"postback,omitempty"`
+}
+
+type RichMessageEvent struct {
+	EventMeta
+	TemplateID TemplateID     `json:"template_id"`
+	Elements   []EventElement `json:"elements,omitempty"`
+}
+
+// FileEvent is a file uploaded to the chat. Width, Height and
+// thumbnails are set for images only.
+type FileEvent struct {
+	EventMeta
+	Name            string `json:"name"`
+	URL             string `json:"url"`
+	ContentType     string `json:"content_type"`
+	Size            int64  `json:"size"`
+	Width           int    `json:"width,omitempty"`
+	Height          int    `json:"height,omitempty"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	Thumbnail2xURL  string `json:"thumbnail2x_url,omitempty"`
+	AlternativeText string `json:"alternative_text,omitempty"`
+}
+
+// FilledFormEvent is a form submitted by customer, e.g. pre-chat survey.
+type FilledFormEvent struct {
+	EventMeta
+	FormID   string       `json:"form_id"`
+	FormType string       `json:"form_type"`
+	Fields   []*FormField `json:"fields"`
+}
+
+// Answers returns answers by label of the question.
+func (e *FilledFormEvent) Answers() map[string]string {
+	answers := make(map[string]string, len(e.Fields))
+	for _, field := range e.Fields {
+		if field.Label != "" {
+			answers[field.Label] = field.Text()
+		}
+	}
+	return answers
+}
+
+// SystemMessageEvent is a message generated by LiveChat, e.g. about
+// the chat being transferred.
+type SystemMessageEvent struct {
+	EventMeta
+	Text              string            `json:"text,omitempty"`
+	SystemMessageType string            `json:"system_message_type"`
+	TextVars          map[string]string `json:"text_vars,omitempty"`
+	Recipients        string            `json:"recipients,omitempty"`
+}
+
+type CustomEvent struct {
+	EventMeta
+	Content json.RawMessage `json:"content,omitempty"`
+}
+
+// UnknownEvent keeps raw payload of event of type which is not modeled.
+type UnknownEvent struct {
+	EventMeta
+	Raw json.RawMessage `json:"-"`
+}
+
+func (e *UnknownEvent) MarshalJSON() ([]byte, error) { return e.Raw, nil }
+
+// DecodeEvent decodes event into the struct of its type.
+func DecodeEvent(data []byte) (ChatEvent, error) {
+	var meta EventMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("event: %w", err)
+	}
+
+	var event ChatEvent
+	switch meta.Type {
+	case EventTypeMessage:
+		event = &MessageEvent{}
+	case EventTypeRichMessage:
+		event = &RichMessageEvent{}
+	case EventTypeFile:
+		event = &FileEvent{}
+	case EventTypeFilledForm:
+		event = &FilledFormEvent{}
+	case EventTypeSystemMessage:
+		event = &SystemMessageEvent{}
+	case EventTypeCustom:
+		event = &CustomEvent{}
+	default:
+		return &UnknownEvent{EventMeta: meta, Raw: append(json.RawMessage{}, data...)}, nil
+	}
+
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, fmt.Errorf("event (type: %s): %w", meta.Type, err)
+	}
+	return event, nil
+}
+
+// AnyEvent decodes event of any type, ChatEvent is nil if event is null.
+type AnyEvent struct {
+	ChatEvent
+}
+
+func (e *AnyEvent) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		e.ChatEvent = nil
+		return nil
+	}
+
+	event, err := DecodeEvent(data)
+	if err != nil {
+		return err
+	}
+	e.ChatEvent = event
+	return nil
+}
+
+func (e AnyEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.ChatEvent)
+}
+
+// Events decodes list of events of any type.
+type Events []ChatEvent
+
+func (e *Events) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	events := make(Events, 0, len(raw))
+	for _, item := range raw {
+		event, err := DecodeEvent(item)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	*e = events
+	return nil
+}
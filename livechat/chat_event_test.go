@@ -0,0 +1,67 @@
+package livechat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DecodeEvent(t *testing.T) {
+	var events Events
+	assert.NoError(t, json.Unmarshal([]byte(`[
+		{"id": "e1", "type": "message", "author_id": "c1", "text": "Hi", "postback": {"id": "p1", "thread_id": "t1", "event_id": "e0"}},
+		{"id": "e2", "type": "file", "author_id": "c1", "name": "cv.pdf", "url": "https://cdn/cv.pdf", "content_type": "application/pdf", "size": 1024},
+		{"id": "e3", "type": "filled_form", "form_id": "f1", "form_type": "postchat", "fields": [{"type": "rating", "label": "Rate", "answer": "good"}]},
+		{"id": "e4", "type": "system_message", "system_message_type": "routing.assigned", "text_vars": {"agent": "John"}},
+		{"id": "e5", "type": "rich_message", "template_id": "cards", "elements": [{"title": "Card"}]},
+		{"id": "e6", "type": "custom", "content": {"any": "thing"}},
+		{"id": "e7", "type": "sticker", "url": "https://cdn/sticker.gif"}
+	]`), &events))
+
+	if !assert.Len(t, events, 7) {
+		return
+	}
+
+	message := events[0].(*MessageEvent)
+	assert.Equal(t, "Hi", message.Text)
+	assert.Equal(t, "e0", message.Postback.EventID)
+	assert.Equal(t, "c1", message.Meta().AuthorID)
+
+	file := events[1].(*FileEvent)
+	assert.Equal(t, "application/pdf", file.ContentType)
+	assert.Equal(t, int64(1024), file.Size)
+
+	form := events[2].(*FilledFormEvent)
+	assert.Equal(t, map[string]string{"Rate": "good"}, form.Answers())
+
+	system := events[3].(*SystemMessageEvent)
+	assert.Equal(t, "routing.assigned", system.SystemMessageType)
+	assert.Equal(t, "John", system.TextVars["agent"])
+
+	assert.Equal(t, TemplateCards, events[4].(*RichMessageEvent).TemplateID)
+	assert.JSONEq(t, `{"any": "thing"}`, string(events[5].(*CustomEvent).Content))
+
+	unknown := events[6].(*UnknownEvent)
+	assert.Equal(t, EventType("sticker"), unknown.Meta().Type)
+	raw, err := json.Marshal(unknown)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "e7", "type": "sticker", "url": "https://cdn/sticker.gif"}`, string(raw))
+}
+
+func Test_AnyEvent(t *testing.T) {
+	var push PushIncomingMessage
+	assert.NoError(t, json.Unmarshal([]byte(`{"action": "incoming_event", "payload": {"chat_id": "c", "event": {"id": "e1", "type": "file", "content_type": "image/png"}}}`), &push))
+	assert.Equal(t, "e1", push.GetEventID())
+
+	raw, err := json.Marshal(push.Payload.Event)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": "e1", "type": "file", "name": "", "url": "", "content_type": "image/png", "size": 0}`, string(raw))
+
+	push = PushIncomingMessage{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"action": "incoming_event", "payload": {"event": null}}`), &push))
+	assert.Nil(t, push.Payload.Event.ChatEvent)
+	assert.Equal(t, "", push.GetEventID())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"payload": {"event": {"type": "file", "size": "big"}}}`), &push))
+}
@@ -0,0 +1,69 @@
+package livechat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PushIncomingChat_Decode(t *testing.T) {
+	raw := `{
+		"webhook_id": "w1",
+		"action": "incoming_chat",
+		"license_id": 1234,
+		"payload": {
+			"chat": {
+				"id": "chat_1",
+				"users": [
+					{"id": "agent@example.com", "type": "agent", "name": "Agent", "present": true},
+					{"id": "customer_1", "type": "customer", "name": "John", "email": "john@example.com", "present": true,
+					 "last_visit": {"ip": "1.2.3.4", "geolocation": {"country_code": "PL"}},
+					 "session_fields": [{"plan": "premium"}]}
+				],
+				"access": {"group_ids": [0, 2]},
+				"properties": {"routing": {"continuous": false, "priority": 3}},
+				"thread": {
+					"id": "thread_1",
+					"active": true,
+					"user_ids": ["customer_1"],
+					"created_at": "2021-01-01T10:00:00.000000Z",
+					"tags": ["vip"],
+					"events": [
+						{"id": "e1", "type": "filled_form", "form_id": "f1", "form_type": "prechat", "fields": [
+							{"type": "name", "label": "Name:", "answer": "John"},
+							{"type": "checkbox", "label": "Topics", "answer": [{"id": "1", "label": "Billing"}, {"id": "2", "label": "API"}]}
+						]},
+						{"id": "e2", "type": "message", "author_id": "customer_1", "text": "Hi!", "visibility": "all"}
+					]
+				}
+			},
+			"transferred_from": {"group_ids": [1]}
+		}
+	}`
+
+	var push PushIncomingChat
+	assert.NoError(t, json.Unmarshal([]byte(raw), &push))
+
+	chat := push.Payload.Chat
+	assert.Equal(t, ChatID("chat_1"), push.GetChatID())
+	assert.Equal(t, []int{0, 2}, chat.GroupIDs())
+	assert.Equal(t, []int{1}, push.Payload.TransferredFrom.GroupIDs)
+
+	customer := chat.Customer()
+	if assert.NotNil(t, customer) {
+		assert.Equal(t, "john@example.com", customer.Email)
+		assert.Equal(t, "PL", customer.LastVisit.Geolocation.CountryCode)
+		plan, _ := customer.SessionField("plan")
+		assert.Equal(t, "premium", plan)
+	}
+
+	priority, ok := chat.Properties.Get("routing.priority")
+	assert.True(t, ok)
+	assert.Equal(t, "3", priority)
+	_, ok = chat.Properties.Get("routing.missing")
+	assert.False(t, ok)
+
+	assert.Len(t, chat.Thread.Events, 2)
+	assert.Equal(t, map[string]string{"Name:": "John", "Topics": "Billing, API"}, chat.Thread.Survey())
+}
@@ -38,9 +38,9 @@ type EventMessage struct {
 }
 
 type EventElement struct {
-	Title    string        `json:"title"`
-	SubTitle string        `json:"subtitle"`
-	Image    EventImage    `json:"image,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	SubTitle string        `json:"subtitle,omitempty"`
+	Image    *EventImage   `json:"image,omitempty"`
 	Button   []EventButton `json:"buttons,omitempty"`
 }
 
@@ -49,11 +49,12 @@ type EventImage struct {
 }
 
 type EventButton struct {
-	Text       string     `json:"text"`
-	Type       ButtonType `json:"type"`
-	Value      string     `json:"value"`
-	PostbackID string     `json:"postback_id"`
-	UserID     []string   `json:"user_ids"`
+	Text          string     `json:"text"`
+	Type          ButtonType `json:"type"`
+	Value         string     `json:"value"`
+	PostbackID    string     `json:"postback_id"`
+	UserID        []string   `json:"user_ids"`
+	WebviewHeight string     `json:"webview_height,omitempty"`
 }
 
 func BuildMessage(chatID ChatID, text string) *Event {
@@ -65,27 +66,3 @@ func BuildMessage(chatID ChatID, text string) *Event {
 		},
 	}
 }
-
-func BuildButtonMessage(chatID ChatID, title, subtitle, text string) *Event {
-	return &Event{
-		ChatID: chatID,
-		Event: EventMessage{
-			Type:       EventTypeRichMessage,
-			TemplateID: TemplateCards,
-			Elements: []EventElement{{
-				Title:    title,
-				SubTitle: subtitle,
-				Image: EventImage{
-					URL: "https://en.meming.world/images/en/thumb/2/2c/Surprised_Pikachu_HD.jpg/300px-Surprised_Pikachu_HD.jpg",
-				},
-				Button: []EventButton{{
-					Text:       text,
-					Type:       ButtonTypeMessage,
-					Value:      text,
-					PostbackID: "to tez nie wiem co to za bardzo",
-					UserID:     []string{},
-				}},
-			}},
-		},
-	}
-}
@@ -0,0 +1,239 @@
+package livechat
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits of rich messages accepted by the platform.
+const (
+	MaxCards            = 10
+	MaxCardButtons      = 3
+	MaxQuickReplies     = 13
+	MaxTitleLength      = 80
+	MaxSubtitleLength   = 80
+	MaxButtonTextLength = 20
+	MaxPostbackIDLength = 1000
+)
+
+const (
+	WebviewHeightCompact = "compact"
+	WebviewHeightTall    = "tall"
+	WebviewHeightFull    = "full"
+)
+
+// RichMessageBuilder builds rich message event. Card, Image and Button
+// calls can be chained, Image and Button apply to the last card.
+type RichMessageBuilder struct {
+	event *Event
+}
+
+// NewCards starts rich message with cards template. Message
+// with more than one card is displayed as a carousel.
+func NewCards(chatID ChatID) *RichMessageBuilder {
+	return newRichMessage(chatID, TemplateCards)
+}
+
+// NewQuickReplies starts rich message with question and buttons
+// displayed as quick replies below it.
+func NewQuickReplies(chatID ChatID, text string) *RichMessageBuilder {
+	return newRichMessage(chatID, TemplateQuickReply).Card(text, "")
+}
+
+// NewSticker starts rich message with image displayed as a sticker.
+func NewSticker(chatID ChatID, imageURL string) *RichMessageBuilder {
+	return newRichMessage(chatID, TemplateSticker).Card("", "").Image(imageURL)
+}
+
+func newRichMessage(chatID ChatID, template TemplateID) *RichMessageBuilder {
+	return &RichMessageBuilder{event: &Event{
+		ChatID: chatID,
+		Event: EventMessage{
+			Type:       EventTypeRichMessage,
+			TemplateID: template,
+			Elements:   []EventElement{},
+		},
+	}}
+}
+
+// Card appends new element to the message.
+func (b *RichMessageBuilder) Card(title, subtitle string) *RichMessageBuilder {
+	b.event.Event.Elements = append(b.event.Event.Elements, EventElement{
+		Title:    title,
+		SubTitle: subtitle,
+	})
+	return b
+}
+
+func (b *RichMessageBuilder) Image(url string) *RichMessageBuilder {
+	if element := b.last(); element != nil {
+		element.Image = &EventImage{URL: url}
+	}
+	return b
+}
+
+func (b *RichMessageBuilder) Button(buttons ...EventButton) *RichMessageBuilder {
+	if element := b.last(); element != nil {
+		element.Button = append(element.Button, buttons...)
+	}
+	return b
+}
+
+// Event returns built message without validation.
+func (b *RichMessageBuilder) Event() *Event { return b.event }
+
+// Build returns built message if it fits into limits of the platform.
+func (b *RichMessageBuilder) Build() (*Event, error) {
+	if err := b.event.Validate(); err != nil {
+		return nil, err
+	}
+	return b.event, nil
+}
+
+func (b *RichMessageBuilder) last() *EventElement {
+	elements := b.event.Event.Elements
+	if len(elements) == 0 {
+		return nil
+	}
+	return &elements[len(elements)-1]
+}
+
+// MessageButton sends its text as customer's message when clicked.
+func MessageButton(text, postbackID string) EventButton {
+	return newButton(ButtonTypeMessage, text, text, postbackID)
+}
+
+func URLButton(text, url, postbackID string) EventButton {
+	return newButton(ButtonTypeUrl, text, url, postbackID)
+}
+
+func PhoneButton(text, phone, postbackID string) EventButton {
+	return newButton(ButtonTypePhone, text, phone, postbackID)
+}
+
+// WebViewButton opens url inside chat widget, height is one of
+// WebviewHeightCompact, WebviewHeightTall or WebviewHeightFull.
+func WebViewButton(text, url, height, postbackID string) EventButton {
+	button := newButton(ButtonTypeWebView, text, url, postbackID)
+	button.WebviewHeight = height
+	return button
+}
+
+func newButton(buttonType ButtonType, text, value, postbackID string) EventButton {
+	return EventButton{
+		Text:       text,
+		Type:       buttonType,
+		Value:      value,
+		PostbackID: postbackID,
+		UserID:     []string{},
+	}
+}
+
+// Validate checks message against limits of the platform. Plain
+// messages only need a text.
+func (r *Event) Validate() error {
+	msg := r.Event
+
+	switch msg.Type {
+	case EventTypeMessage:
+		if msg.Text == "" {
+			return errors.New("event: message cannot be empty")
+		}
+		return nil
+	case EventTypeRichMessage:
+	default:
+		return fmt.Errorf("event: unsupported type %q", msg.Type)
+	}
+
+	if len(msg.Elements) == 0 {
+		return errors.New("event: rich message requires at least one element")
+	}
+
+	switch msg.TemplateID {
+	case TemplateCards:
+		if len(msg.Elements) > MaxCards {
+			return fmt.Errorf("event: cards template accepts up to %d elements, got %d", MaxCards, len(msg.Elements))
+		}
+		for i, element := range msg.Elements {
+			if element.Title == "" && element.Image == nil {
+				return fmt.Errorf("event: card #%d requires title or image", i)
+			}
+			if len(element.Button) > MaxCardButtons {
+				return fmt.Errorf("event: card #%d accepts up to %d buttons, got %d", i, MaxCardButtons, len(element.Button))
+			}
+		}
+	case TemplateQuickReply:
+		if len(msg.Elements) != 1 {
+			return fmt.Errorf("event: quick replies template requires exactly one element, got %d", len(msg.Elements))
+		}
+		if len(msg.Elements[0].Button) == 0 || len(msg.Elements[0].Button) > MaxQuickReplies {
+			return fmt.Errorf("event: quick replies template requires from 1 to %d buttons, got %d", MaxQuickReplies, len(msg.Elements[0].Button))
+		}
+	case TemplateSticker:
+		if len(msg.Elements) != 1 || msg.Elements[0].Image == nil || msg.Elements[0].Image.URL == "" {
+			return errors.New("event: sticker template requires exactly one element with image")
+		}
+		if len(msg.Elements[0].Button) > 0 {
+			return errors.New("event: sticker cannot have buttons")
+		}
+	default:
+		return fmt.Errorf("event: unsupported template %q", msg.TemplateID)
+	}
+
+	for i, element := range msg.Elements {
+		if utf8.RuneCountInString(element.Title) > MaxTitleLength {
+			return fmt.Errorf("event: element #%d: title is longer than %d characters", i, MaxTitleLength)
+		}
+		if utf8.RuneCountInString(element.SubTitle) > MaxSubtitleLength {
+			return fmt.Errorf("event: element #%d: subtitle is longer than %d characters", i, MaxSubtitleLength)
+		}
+		if element.Image != nil && element.Image.URL == "" {
+			return fmt.Errorf("event: element #%d: image requires url", i)
+		}
+
+		for j, button := range element.Button {
+			if err := button.validate(); err != nil {
+				return fmt.Errorf("event: element #%d: button #%d: %w", i, j, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *EventButton) validate() error {
+	if b.Text == "" {
+		return errors.New("text cannot be empty")
+	}
+	if utf8.RuneCountInString(b.Text) > MaxButtonTextLength {
+		return fmt.Errorf("text is longer than %d characters", MaxButtonTextLength)
+	}
+	if b.PostbackID == "" {
+		return errors.New("postback id cannot be empty")
+	}
+	if len(b.PostbackID) > MaxPostbackIDLength {
+		return fmt.Errorf("postback id is longer than %d characters", MaxPostbackIDLength)
+	}
+
+	switch b.Type {
+	case ButtonTypeMessage:
+	case ButtonTypeUrl, ButtonTypePhone:
+		if b.Value == "" {
+			return fmt.Errorf("%s button requires value", b.Type)
+		}
+	case ButtonTypeWebView:
+		if b.Value == "" {
+			return errors.New("webview button requires value")
+		}
+		switch b.WebviewHeight {
+		case WebviewHeightCompact, WebviewHeightTall, WebviewHeightFull:
+		default:
+			return fmt.Errorf("webview button has invalid height %q", b.WebviewHeight)
+		}
+	default:
+		return fmt.Errorf("unsupported type %q", b.Type)
+	}
+
+	return nil
+}
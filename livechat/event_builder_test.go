@@ -0,0 +1,86 @@
+package livechat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const definedChatID = ChatID("custom_chat_id")
+
+func Test_RichMessageBuilder_Carousel(t *testing.T) {
+	event, err := NewCards(definedChatID).
+		Card("First", "subtitle").Image("https://example.com/1.png").
+		Button(MessageButton("Yes", "answer_yes"), URLButton("Open", "https://example.com", "open_page")).
+		Card("Second", "").
+		Button(PhoneButton("Call us", "+48123456789", "call"), WebViewButton("Form", "https://example.com/form", WebviewHeightFull, "form")).
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, TemplateCards, event.Event.TemplateID)
+	assert.Len(t, event.Event.Elements, 2)
+	assert.Equal(t, "https://example.com/1.png", event.Event.Elements[0].Image.URL)
+	assert.Nil(t, event.Event.Elements[1].Image)
+	assert.Equal(t, "answer_yes", event.Event.Elements[0].Button[0].PostbackID)
+	assert.Equal(t, ButtonTypeWebView, event.Event.Elements[1].Button[1].Type)
+	assert.Equal(t, WebviewHeightFull, event.Event.Elements[1].Button[1].WebviewHeight)
+}
+
+func Test_RichMessageBuilder_QuickReplies(t *testing.T) {
+	event, err := NewQuickReplies(definedChatID, "Did it help?").
+		Button(MessageButton("Yes", "helped"), MessageButton("No", "not_helped")).
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, TemplateQuickReply, event.Event.TemplateID)
+	assert.Equal(t, "Did it help?", event.Event.Elements[0].Title)
+	assert.Len(t, event.Event.Elements[0].Button, 2)
+}
+
+func Test_RichMessageBuilder_Sticker(t *testing.T) {
+	event, err := NewSticker(definedChatID, "https://example.com/sticker.png").Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, TemplateSticker, event.Event.TemplateID)
+	assert.Equal(t, "https://example.com/sticker.png", event.Event.Elements[0].Image.URL)
+}
+
+func Test_RichMessageBuilder_Limits(t *testing.T) {
+	tooManyCards := NewCards(definedChatID)
+	for i := 0; i <= MaxCards; i++ {
+		tooManyCards.Card("title", "")
+	}
+
+	tooManyReplies := NewQuickReplies(definedChatID, "question")
+	for i := 0; i <= MaxQuickReplies; i++ {
+		tooManyReplies.Button(MessageButton("reply", "reply"))
+	}
+
+	tests := map[string]*RichMessageBuilder{
+		"no elements":          NewCards(definedChatID),
+		"too many cards":       tooManyCards,
+		"too many buttons":     NewCards(definedChatID).Card("title", "").Button(MessageButton("1", "1"), MessageButton("2", "2"), MessageButton("3", "3"), MessageButton("4", "4")),
+		"too long title":       NewCards(definedChatID).Card(strings.Repeat("a", MaxTitleLength+1), ""),
+		"too long button text": NewCards(definedChatID).Card("title", "").Button(MessageButton(strings.Repeat("a", MaxButtonTextLength+1), "postback")),
+		"missing postback id":  NewCards(definedChatID).Card("title", "").Button(MessageButton("Yes", "")),
+		"url without value":    NewCards(definedChatID).Card("title", "").Button(URLButton("Open", "", "open")),
+		"webview height":       NewCards(definedChatID).Card("title", "").Button(WebViewButton("Open", "https://example.com", "huge", "open")),
+		"no quick replies":     NewQuickReplies(definedChatID, "question"),
+		"too many replies":     tooManyReplies,
+		"sticker without url":  NewSticker(definedChatID, ""),
+		"sticker with button":  NewSticker(definedChatID, "https://example.com/sticker.png").Button(MessageButton("Yes", "yes")),
+	}
+
+	for name, builder := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := builder.Build()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_Event_Validate_Message(t *testing.T) {
+	assert.NoError(t, BuildMessage(definedChatID, "Hello").Validate())
+	assert.Error(t, BuildMessage(definedChatID, "").Validate())
+}
@@ -3,6 +3,13 @@ package livechat
 type Push interface {
 	GetAction() string
 	GetLicenseID() LicenseID
+	GetChatID() ChatID
+}
+
+// EventPush is implemented by pushes about a single chat event.
+type EventPush interface {
+	Push
+	GetEventID() string
 }
 
 type InstallApplicationWebhook struct {
@@ -16,31 +23,37 @@ type PushIncomingMessage struct {
 	Action    string    `json:"action"`
 	LicenseID LicenseID `json:"license_id,omitempty"`
 	Payload   struct {
-		ChatID   ChatID `json:"chat_id"`
-		ThreadID string `json:"thread_id,omitempty"`
-		Event    struct {
-			Type     string `json:"type"`
-			Text     string `json:"text"`
-			AuthorID string `json:"author_id"`
-		} `json:"event"`
+		ChatID   ChatID   `json:"chat_id"`
+		ThreadID string   `json:"thread_id,omitempty"`
+		Event    AnyEvent `json:"event"`
 	} `json:"payload"`
 }
 
 func (m *PushIncomingMessage) GetAction() string       { return m.Action }
 func (m *PushIncomingMessage) GetLicenseID() LicenseID { return m.LicenseID }
+func (m *PushIncomingMessage) GetChatID() ChatID       { return m.Payload.ChatID }
+func (m *PushIncomingMessage) GetEventID() string {
+	if m.Payload.Event.ChatEvent == nil {
+		return ""
+	}
+	return m.Payload.Event.Meta().ID
+}
 
 type PushIncomingChat struct {
 	Action    string    `json:"action"`
 	LicenseID LicenseID `json:"license_id,omitempty"`
 	Payload   struct {
-		Chat struct {
-			ID ChatID `json:"id"`
-		} `json:"chat"`
+		Chat            Chat `json:"chat"`
+		TransferredFrom *struct {
+			GroupIDs []int     `json:"group_ids,omitempty"`
+			AgentIDs []AgentID `json:"agent_ids,omitempty"`
+		} `json:"transferred_from,omitempty"`
 	} `json:"payload"`
 }
 
 func (m *PushIncomingChat) GetAction() string       { return m.Action }
 func (m *PushIncomingChat) GetLicenseID() LicenseID { return m.LicenseID }
+func (m *PushIncomingChat) GetChatID() ChatID       { return m.Payload.Chat.ID }
 
 type PushUserAddedToChat struct {
 	Action    string    `json:"action"`
@@ -56,3 +69,80 @@ type PushUserAddedToChat struct {
 
 func (m *PushUserAddedToChat) GetAction() string       { return m.Action }
 func (m *PushUserAddedToChat) GetLicenseID() LicenseID { return m.LicenseID }
+func (m *PushUserAddedToChat) GetChatID() ChatID       { return m.Payload.ChatID }
+
+type PushIncomingRichMessagePostback struct {
+	Action    string    `json:"action"`
+	LicenseID LicenseID `json:"license_id,omitempty"`
+	Payload   struct {
+		UserID   string `json:"user_id"`
+		ChatID   ChatID `json:"chat_id"`
+		ThreadID string `json:"thread_id"`
+		EventID  string `json:"event_id"`
+		Postback struct {
+			ID      string `json:"id"`
+			Toggled bool   `json:"toggled"`
+		} `json:"postback"`
+	} `json:"payload"`
+}
+
+func (m *PushIncomingRichMessagePostback) GetAction() string       { return m.Action }
+func (m *PushIncomingRichMessagePostback) GetLicenseID() LicenseID { return m.LicenseID }
+func (m *PushIncomingRichMessagePostback) GetChatID() ChatID       { return m.Payload.ChatID }
+
+// PushChatDeactivated is sent when the chat is closed, by any of its
+// users or due to inactivity.
+type PushChatDeactivated struct {
+	Action    string    `json:"action"`
+	LicenseID LicenseID `json:"license_id,omitempty"`
+	Payload   struct {
+		ChatID   ChatID `json:"chat_id"`
+		ThreadID string `json:"thread_id"`
+		UserID   string `json:"user_id,omitempty"`
+	} `json:"payload"`
+}
+
+func (m *PushChatDeactivated) GetAction() string       { return m.Action }
+func (m *PushChatDeactivated) GetLicenseID() LicenseID { return m.LicenseID }
+func (m *PushChatDeactivated) GetChatID() ChatID       { return m.Payload.ChatID }
+
+type PushUserRemovedFromChat struct {
+	Action    string    `json:"action"`
+	LicenseID LicenseID `json:"license_id,omitempty"`
+	Payload   struct {
+		ChatID      ChatID `json:"chat_id"`
+		ThreadID    string `json:"thread_id"`
+		UserID      string `json:"user_id"`
+		Reason      string `json:"reason,omitempty"`
+		RequesterID string `json:"requester_id,omitempty"`
+	} `json:"payload"`
+}
+
+func (m *PushUserRemovedFromChat) GetAction() string       { return m.Action }
+func (m *PushUserRemovedFromChat) GetLicenseID() LicenseID { return m.LicenseID }
+func (m *PushUserRemovedFromChat) GetChatID() ChatID       { return m.Payload.ChatID }
+
+type PushChatTransferred struct {
+	Action    string    `json:"action"`
+	LicenseID LicenseID `json:"license_id,omitempty"`
+	Payload   struct {
+		ChatID        ChatID `json:"chat_id"`
+		ThreadID      string `json:"thread_id,omitempty"`
+		RequesterID   string `json:"requester_id,omitempty"`
+		Reason        string `json:"reason,omitempty"`
+		TransferredTo struct {
+			GroupIDs []int     `json:"group_ids,omitempty"`
+			AgentIDs []AgentID `json:"agent_ids,omitempty"`
+		} `json:"transferred_to"`
+		// Queue is set if the chat waits in queue of target group.
+		Queue *struct {
+			Position int    `json:"position"`
+			WaitTime int    `json:"wait_time"`
+			QueuedAt string `json:"queued_at"`
+		} `json:"queue,omitempty"`
+	} `json:"payload"`
+}
+
+func (m *PushChatTransferred) GetAction() string       { return m.Action }
+func (m *PushChatTransferred) GetLicenseID() LicenseID { return m.LicenseID }
+func (m *PushChatTransferred) GetChatID() ChatID       { return m.Payload.ChatID }
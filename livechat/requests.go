@@ -1,5 +1,7 @@
 package livechat
 
+import "encoding/json"
+
 type Request interface {
 	Endpoint() string
 }
@@ -8,6 +10,13 @@ type ClientAuthorize interface {
 	WithClientID(ClientID)
 }
 
+// Idempotent is implemented by requests which can be safely
+// sent again when the result of previous attempt is unknown.
+type Idempotent interface {
+	Request
+	Idempotent()
+}
+
 const (
 	createBotEndpoint  = "/configuration/action/create_bot"
 	deleteBotEndpoint  = "/configuration/action/delete_bot"
@@ -31,6 +40,8 @@ const (
 type CreateBotRequest struct {
 	Name     string   `json:"name"`
 	ClientID ClientID `json:"owner_client_id,omitempty"`
+	// MaxChatsCount limits chats served by bot at once, unlimited if empty.
+	MaxChatsCount int `json:"max_chats_count,omitempty"`
 }
 
 func (r *CreateBotRequest) Endpoint() string { return createBotEndpoint }
@@ -49,13 +60,17 @@ type DeleteBotResponse struct{}
 
 type ListBotsRequest struct {
 	All bool `json:"all,omitempty"`
+	// Fields are additional properties of bots to return, e.g. "max_chats_count".
+	Fields []string `json:"fields,omitempty"`
 }
 
 func (r *ListBotsRequest) Endpoint() string { return listBotsEndpoint }
+func (r *ListBotsRequest) Idempotent()      {}
 
 type ListBotResponse struct {
-	ID   AgentID `json:"id"`
-	Name string  `json:"name"`
+	ID            AgentID `json:"id"`
+	Name          string  `json:"name"`
+	MaxChatsCount int     `json:"max_chats_count,omitempty"`
 }
 
 type RegisterWebhookRequest struct {
@@ -84,12 +99,37 @@ func (r *UnregisterWebhookRequest) WithClientID(cid ClientID) { r.ClientID = cid
 type UnregisterWebhookResponse struct{}
 
 type TransferChatRequest struct {
-	ID     ChatID `json:"id"`
-	Target struct {
-		Type string    `json:"type"`
-		IDs  []AgentID `json:"ids"`
-	} `json:"target"`
-	Force bool `json:"force,omitempty"`
+	ID     ChatID         `json:"id"`
+	Target TransferTarget `json:"target"`
+	Force  bool           `json:"force,omitempty"`
+}
+
+const (
+	TransferTargetAgent = "agent"
+	TransferTargetGroup = "group"
+)
+
+// TransferTarget points agents (IDs) or groups (GroupIDs, when
+// Type is "group") which the chat is transferred to.
+type TransferTarget struct {
+	Type     string    `json:"type"`
+	IDs      []AgentID `json:"ids"`
+	GroupIDs []int     `json:"-"`
+}
+
+func (t TransferTarget) MarshalJSON() ([]byte, error) {
+	var ids interface{} = t.IDs
+	if t.Type == TransferTargetGroup {
+		ids = t.GroupIDs
+	}
+
+	return json.Marshal(struct {
+		Type string      `json:"type"`
+		IDs  interface{} `json:"ids"`
+	}{
+		Type: t.Type,
+		IDs:  ids,
+	})
 }
 
 func (r *TransferChatRequest) Endpoint() string { return transferChatEndpoint }
@@ -105,6 +145,7 @@ type EnableLicenseWebhookRequest struct {
 }
 
 func (r *EnableLicenseWebhookRequest) Endpoint() string { return enableLicenseWebhookEndpoint }
+func (r *EnableLicenseWebhookRequest) Idempotent()      {}
 
 type EnableLicenseWebhookResponse struct{}
 
@@ -113,6 +154,7 @@ type DisableLicenseWebhookRequest struct {
 }
 
 func (r *DisableLicenseWebhookRequest) Endpoint() string { return disableLicenseWebhookEndpoint }
+func (r *DisableLicenseWebhookRequest) Idempotent()      {}
 
 type DisableLicenseWebhookResponse struct{}
 
@@ -122,12 +164,14 @@ type SetRoutingStatusRequest struct {
 }
 
 func (r *SetRoutingStatusRequest) Endpoint() string { return setRoutingStatusEndpoint }
+func (r *SetRoutingStatusRequest) Idempotent()      {}
 
 type SetRoutingStatusResponse struct{}
 
 type ListAgentsRequest struct{}
 
 func (r *ListAgentsRequest) Endpoint() string { return listAgentsEndpoint }
+func (r *ListAgentsRequest) Idempotent()      {}
 
 type ListAgentsResponse struct {
 	ID            AgentID `json:"id"`
@@ -140,6 +184,7 @@ type ListAgentsForTransferRequest struct {
 }
 
 func (r *ListAgentsForTransferRequest) Endpoint() string { return listAgentsForTransferEndpoint }
+func (r *ListAgentsForTransferRequest) Idempotent()      {}
 
 type ListAgentsForTransferResponse struct {
 	AgentID          AgentID `json:"agent_id"`
@@ -152,6 +197,7 @@ type GetChatRequest struct {
 }
 
 func (r *GetChatRequest) Endpoint() string { return getChatEndpoint }
+func (r *GetChatRequest) Idempotent()      {}
 
 type GetChatResponse struct {
 	ID      ChatID    `json:"id"`
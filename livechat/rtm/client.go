@@ -0,0 +1,216 @@
+package rtm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web"
+	"github.com/sirupsen/logrus"
+)
+
+type livechatConn struct {
+	ws        *websocket.Conn
+	licenseID livechat.LicenseID
+	requestID uint64
+
+	muWrite   *sync.Mutex
+	muPending *sync.Mutex
+	pending   map[string]chan *frame
+
+	pushes    chan livechat.Push
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type errorResponse struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (c *livechatConn) Login(ctx context.Context, payload *LoginRequest) (*LoginResponse, error) {
+	var body LoginResponse
+	if err := c.sendRequest(ctx, payload, &body); err != nil {
+		return nil, fmt.Errorf("login action: %w", err)
+	}
+
+	return &body, nil
+}
+
+func (c *livechatConn) Pushes() <-chan livechat.Push { return c.pushes }
+
+func (c *livechatConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		c.muWrite.Lock()
+		c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		c.muWrite.Unlock()
+
+		err = c.ws.Close()
+	})
+	return err
+}
+
+func (c *livechatConn) sendRequest(ctx context.Context, payload Request, body interface{}) error {
+	if nil == payload {
+		return fmt.Errorf("rtm_client: request body cannot be empty")
+	}
+
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("rtm_client: cannot encode request body: %w", err)
+	}
+
+	requestID := strconv.FormatUint(atomic.AddUint64(&c.requestID, 1), 10)
+	response := make(chan *frame, 1)
+
+	c.muPending.Lock()
+	c.pending[requestID] = response
+	c.muPending.Unlock()
+	defer func() {
+		c.muPending.Lock()
+		delete(c.pending, requestID)
+		c.muPending.Unlock()
+	}()
+
+	c.muWrite.Lock()
+	err = c.ws.WriteJSON(&frame{RequestID: requestID, Action: payload.Action(), Payload: rawPayload})
+	c.muWrite.Unlock()
+
+	logrus.WithError(err).WithField("action", payload.Action()).Debug("Sending RTM request")
+	if err != nil {
+		return fmt.Errorf("rtm_client: %w", err)
+	}
+
+	select {
+	case res := <-response:
+		if res.Success != nil && !*res.Success {
+			return readErrorMessage(payload.Action(), res.Payload)
+		}
+		if body == nil || len(res.Payload) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(res.Payload, body); err != nil {
+			return fmt.Errorf("rtm_client: %w", err)
+		}
+		return nil
+	case <-c.done:
+		return fmt.Errorf("rtm_client: connection closed")
+	case <-ctx.Done():
+		return fmt.Errorf("rtm_client: %w", ctx.Err())
+	}
+}
+
+func (c *livechatConn) readLoop() {
+	defer close(c.pushes)
+	defer c.Close()
+
+	for {
+		var msg frame
+		if err := c.ws.ReadJSON(&msg); err != nil {
+			select {
+			case <-c.done:
+			default:
+				logrus.WithError(err).WithField("license_id", c.licenseID).Warn("RTM connection has been interrupted")
+			}
+			return
+		}
+
+		if msg.Type == "push" {
+			c.dispatchPush(&msg)
+			continue
+		}
+
+		c.muPending.Lock()
+		response, ok := c.pending[msg.RequestID]
+		c.muPending.Unlock()
+		if ok {
+			response <- &msg
+		}
+	}
+}
+
+func (c *livechatConn) dispatchPush(msg *frame) {
+	logEntry := logrus.WithField("license_id", c.licenseID).WithField("action", msg.Action)
+
+	push, ok := NewPush(msg.Action)
+	if !ok {
+		logEntry.Debug("Skipped unsupported RTM push")
+		return
+	}
+
+	// RTM pushes share the same envelope (action and payload) as webhooks,
+	// so they can be decoded into the same structures.
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		logEntry.WithError(err).Error("Cannot encode RTM push")
+		return
+	}
+	if err := json.Unmarshal(raw, push); err != nil {
+		logEntry.WithError(err).Error("Cannot decode RTM push")
+		return
+	}
+	withLicenseID(push, c.licenseID)
+
+	select {
+	case c.pushes <- push:
+	case <-c.done:
+	}
+}
+
+func (c *livechatConn) pingLoop() {
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), PingInterval)
+			if err := c.sendRequest(ctx, &PingRequest{}, nil); err != nil {
+				logrus.WithError(err).WithField("license_id", c.licenseID).Warn("Cannot ping RTM connection")
+			}
+			cancel()
+		}
+	}
+}
+
+func withLicenseID(push livechat.Push, licenseID livechat.LicenseID) {
+	switch msg := push.(type) {
+	case *livechat.PushIncomingChat:
+		msg.LicenseID = licenseID
+	case *livechat.PushIncomingMessage:
+		msg.LicenseID = licenseID
+	case *livechat.PushUserAddedToChat:
+		msg.LicenseID = licenseID
+	case *livechat.PushIncomingRichMessagePostback:
+		msg.LicenseID = licenseID
+	case *livechat.PushChatDeactivated:
+		msg.LicenseID = licenseID
+	case *livechat.PushUserRemovedFromChat:
+		msg.LicenseID = licenseID
+	case *livechat.PushChatTransferred:
+		msg.LicenseID = licenseID
+	}
+}
+
+func readErrorMessage(action string, payload json.RawMessage) error {
+	body := struct {
+		Error errorResponse `json:"error"`
+	}{}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return fmt.Errorf("rtm_client: cannot decode response: %w", err)
+	}
+
+	logrus.WithField("type", fmt.Sprintf("rtm_type: %s", body.Error.Type)).Warn(body.Error.Message)
+	return &web.APIError{Type: body.Error.Type, Message: body.Error.Message, Endpoint: action}
+}
@@ -0,0 +1,133 @@
+package rtm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/livechat/onboarding/livechat/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Client_Login(t *testing.T) {
+	server := helperServeRTM(t, func(ws *websocket.Conn, req *frame) {
+		var payload LoginRequest
+		json.Unmarshal(req.Payload, &payload)
+
+		success := payload.Token == "Bearer abcd"
+		ws.WriteJSON(&frame{
+			RequestID: req.RequestID,
+			Action:    req.Action,
+			Type:      "response",
+			Success:   &success,
+			Payload:   json.RawMessage(`{"my_profile": {"id": "agent_1"}}`),
+		})
+	})
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), helperWsURL(server), livechat.LicenseID(1234))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	response, err := conn.Login(context.Background(), &LoginRequest{Token: "Bearer abcd"})
+	assert.NoError(t, err)
+	assert.Equal(t, "agent_1", response.MyProfile.ID)
+}
+
+func Test_Client_Login_Error(t *testing.T) {
+	server := helperServeRTM(t, func(ws *websocket.Conn, req *frame) {
+		success := false
+		ws.WriteJSON(&frame{
+			RequestID: req.RequestID,
+			Action:    req.Action,
+			Type:      "response",
+			Success:   &success,
+			Payload:   json.RawMessage(`{"error": {"type": "authentication", "message": "Invalid access token"}}`),
+		})
+	})
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), helperWsURL(server), livechat.LicenseID(1234))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Login(context.Background(), &LoginRequest{Token: "Bearer abcd"})
+	assert.True(t, errors.Is(err, web.ErrAuthentication))
+}
+
+func Test_Client_Pushes(t *testing.T) {
+	server := helperServeRTM(t, func(ws *websocket.Conn, req *frame) {
+		ws.WriteJSON(&frame{Action: "incoming_chat", Type: "push", Payload: json.RawMessage(`{"chat": {"id": "chat_1"}}`)})
+		ws.WriteJSON(&frame{Action: "unknown_push", Type: "push", Payload: json.RawMessage(`{}`)})
+		ws.WriteJSON(&frame{Action: "incoming_event", Type: "push", Payload: json.RawMessage(`{"chat_id": "chat_1", "event": {"type": "message", "text": "Hello"}}`)})
+		ws.WriteJSON(&frame{Action: "chat_transferred", Type: "push", Payload: json.RawMessage(`{"chat_id": "chat_1", "reason": "manual", "transferred_to": {"group_ids": [2]}}`)})
+	})
+	defer server.Close()
+
+	conn, err := Dial(context.Background(), helperWsURL(server), livechat.LicenseID(1234))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	conn.Login(ctx, &LoginRequest{})
+
+	chat := helperReadPush(t, conn).(*livechat.PushIncomingChat)
+	assert.Equal(t, livechat.ChatID("chat_1"), chat.Payload.Chat.ID)
+	assert.Equal(t, livechat.LicenseID(1234), chat.GetLicenseID())
+
+	event := helperReadPush(t, conn).(*livechat.PushIncomingMessage)
+	if message, ok := event.Payload.Event.ChatEvent.(*livechat.MessageEvent); assert.True(t, ok) {
+		assert.Equal(t, "Hello", message.Text)
+	}
+	assert.Equal(t, livechat.LicenseID(1234), event.GetLicenseID())
+
+	transferred := helperReadPush(t, conn).(*livechat.PushChatTransferred)
+	assert.Equal(t, livechat.ChatID("chat_1"), transferred.GetChatID())
+	assert.Equal(t, []int{2}, transferred.Payload.TransferredTo.GroupIDs)
+	assert.Equal(t, livechat.LicenseID(1234), transferred.GetLicenseID())
+}
+
+func helperServeRTM(t *testing.T, onRequest func(*websocket.Conn, *frame)) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		for {
+			var req frame
+			if err := ws.ReadJSON(&req); err != nil {
+				return
+			}
+			onRequest(ws, &req)
+		}
+	}))
+}
+
+func helperWsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func helperReadPush(t *testing.T, conn LivechatRTM) livechat.Push {
+	t.Helper()
+
+	select {
+	case push := <-conn.Pushes():
+		return push
+	case <-time.After(time.Second):
+		t.Fatalf("push has not been received")
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package rtm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/livechat/onboarding/livechat"
+)
+
+// PingInterval defines how often connection sends "ping" action
+// to keep the RTM session alive.
+var PingInterval = 15 * time.Second
+
+//go:generate mockery --name LivechatRTM
+type LivechatRTM interface {
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	// Pushes returns channel with every push received by connection.
+	// The channel is closed when the connection is closed.
+	Pushes() <-chan livechat.Push
+	Close() error
+}
+
+func Dial(ctx context.Context, url string, licenseID livechat.LicenseID) (LivechatRTM, error) {
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("rtm_client: %w", err)
+	}
+
+	conn := &livechatConn{
+		ws:        ws,
+		licenseID: licenseID,
+		pending:   make(map[string]chan *frame),
+		pushes:    make(chan livechat.Push, 16),
+		done:      make(chan struct{}),
+		muWrite:   &sync.Mutex{},
+		muPending: &sync.Mutex{},
+	}
+
+	go conn.readLoop()
+	go conn.pingLoop()
+
+	return conn, nil
+}
+
+// NewPush returns an empty push for given action or false
+// if action is not supported by bot.
+func NewPush(action string) (livechat.Push, bool) {
+	switch action {
+	case "incoming_chat":
+		return &livechat.PushIncomingChat{}, true
+	case "incoming_event":
+		return &livechat.PushIncomingMessage{}, true
+	case "user_added_to_chat":
+		return &livechat.PushUserAddedToChat{}, true
+	case "incoming_rich_message_postback":
+		return &livechat.PushIncomingRichMessagePostback{}, true
+	case "chat_deactivated":
+		return &livechat.PushChatDeactivated{}, true
+	case "user_removed_from_chat":
+		return &livechat.PushUserRemovedFromChat{}, true
+	case "chat_transferred":
+		return &livechat.PushChatTransferred{}, true
+	default:
+		return nil, false
+	}
+}
+
+type frame struct {
+	RequestID string          `json:"request_id,omitempty"`
+	Action    string          `json:"action"`
+	Type      string          `json:"type,omitempty"`
+	Success   *bool           `json:"success,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
@@ -0,0 +1,32 @@
+package rtm
+
+const (
+	loginAction = "login"
+	pingAction  = "ping"
+)
+
+type Request interface {
+	Action() string
+}
+
+type LoginRequest struct {
+	Token string `json:"token"`
+}
+
+func (r *LoginRequest) Action() string { return loginAction }
+
+type LoginResponse struct {
+	MyProfile struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"my_profile"`
+	License struct {
+		ID int `json:"id"`
+	} `json:"license"`
+}
+
+type PingRequest struct{}
+
+func (r *PingRequest) Action() string { return pingAction }
+
+type PingResponse struct{}
@@ -5,17 +5,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/auth"
+	"github.com/livechat/onboarding/metrics"
 	"github.com/sirupsen/logrus"
 )
 
 type livechatClient struct {
 	httpClient livechat.Client
 	url        string
+
+	retry   RetryPolicy
+	limiter *limiter
+	// sleep waits before the next attempt, package sleep is used if nil.
+	sleep func(context.Context, time.Duration) error
 }
 
 type errorResponse struct {
@@ -27,7 +34,6 @@ type errorResponse struct {
 
 func (c *livechatClient) sendRequest(ctx context.Context, payload livechat.Request, body interface{}) (*http.Response, error) {
 	var err error
-	var req *http.Request
 
 	if nil == payload {
 		return nil, fmt.Errorf("http_client: request body cannot be empty")
@@ -49,8 +55,53 @@ func (c *livechatClient) sendRequest(ctx context.Context, payload livechat.Reque
 		}
 	}
 
-	url := fmt.Sprintf("%s%s", c.url, payload.Endpoint())
-	req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBody))
+	_, idempotent := payload.(livechat.Idempotent)
+	licenseID, _ := auth.GetLicenseID(ctx)
+
+	var res *http.Response
+	for attempt := 1; ; attempt++ {
+		if err = c.limiter.Wait(ctx, licenseID); err != nil {
+			return nil, fmt.Errorf("http_client: %w", err)
+		}
+
+		res, err = c.do(ctx, payload.Endpoint(), jsonBody)
+		if err == nil {
+			break
+		}
+
+		delay, retry := c.retry.delay(attempt, idempotent, err)
+		if !retry {
+			return nil, err
+		}
+
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"endpoint": payload.Endpoint(),
+			"attempt":  attempt,
+			"delay":    delay,
+		}).Info("Retrying HTTP request")
+		if err = c.wait(ctx, delay); err != nil {
+			return nil, fmt.Errorf("http_client: %w", err)
+		}
+	}
+	defer res.Body.Close()
+
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return res, fmt.Errorf("http_client: %w", err)
+	}
+	return res, nil
+}
+
+func (c *livechatClient) wait(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		return c.sleep(ctx, d)
+	}
+	return sleep(ctx, d)
+}
+
+// do sends a single request. Body of successful response has to be closed by caller.
+func (c *livechatClient) do(ctx context.Context, endpoint string, jsonBody []byte) (*http.Response, error) {
+	url := fmt.Sprintf("%s%s", c.url, endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("http_client: %w", err)
 	}
@@ -67,31 +118,37 @@ func (c *livechatClient) sendRequest(ctx context.Context, payload livechat.Reque
 		req.Header.Add("X-Author-ID", string(authorID))
 	}
 
+	started := time.Now()
 	res, err := c.httpClient.Do(req)
 	if err != nil {
+		metrics.RequestDuration.WithLabelValues(endpoint, "error").Observe(time.Since(started).Seconds())
 		return nil, fmt.Errorf("http_client: %w", err)
 	}
-	defer res.Body.Close()
+	metrics.RequestDuration.WithLabelValues(endpoint, strconv.Itoa(res.StatusCode)).Observe(time.Since(started).Seconds())
 	if res.StatusCode != http.StatusOK {
-		logrus.WithField("url", payload.Endpoint()).WithField("status_code", res.StatusCode).Warn("Received invalid response from WEB API LiveChat")
-		return nil, readErrorMessage(res.Body)
+		defer res.Body.Close()
+		logrus.WithField("url", endpoint).WithField("status_code", res.StatusCode).Warn("Received invalid response from WEB API LiveChat")
+		return nil, readErrorMessage(res, endpoint)
 	}
 
-	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
-		return res, fmt.Errorf("http_client: %w", err)
-	}
 	return res, nil
 }
 
-func readErrorMessage(w io.Reader) error {
-	body := errorResponse{}
-	if err := json.NewDecoder(w).Decode(&body); err != nil {
-		return fmt.Errorf("http_client: cannot decode response: %w", err)
+func readErrorMessage(res *http.Response, endpoint string) error {
+	apiErr := &APIError{
+		StatusCode: res.StatusCode,
+		Endpoint:   endpoint,
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
 	}
 
-	defer func() {
-		logrus.WithField("type", fmt.Sprintf("http_type: %s", body.ErrorMessage.Type)).Warn(body.ErrorMessage.Message)
-	}()
+	body := errorResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		apiErr.Message = http.StatusText(res.StatusCode)
+		return fmt.Errorf("http_client: cannot decode response: %w", apiErr)
+	}
+	apiErr.Type = body.ErrorMessage.Type
+	apiErr.Message = body.ErrorMessage.Message
 
-	return fmt.Errorf("%s (type %s)", body.ErrorMessage.Message, body.ErrorMessage.Type)
+	logrus.WithField("type", fmt.Sprintf("http_type: %s", apiErr.Type)).Warn(apiErr.Message)
+	return apiErr
 }
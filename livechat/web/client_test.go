@@ -4,13 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/auth"
 	"github.com/livechat/onboarding/livechat/mocks"
+	"github.com/livechat/onboarding/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -89,3 +94,220 @@ func Test_Client_WithClientID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "example response", example.Body)
 }
+
+func Test_Client_APIError(t *testing.T) {
+	ctx := auth.WithPAT(context.Background(), "username", "password")
+
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"error": {"type": "validation", "message": "Agent is offline."}}`)),
+	}, nil)
+
+	webService := &livechatClient{
+		httpClient: httpClient,
+		url:        "http://lorem.pl",
+	}
+
+	var example exampleResponse
+	_, err := webService.sendRequest(ctx, &exampleRequest{Data: "random data"}, &example)
+
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.True(t, errors.Is(err, ErrAgentOffline))
+	assert.False(t, errors.Is(err, ErrAgentAlreadyInChat))
+
+	apiErr, ok := AsAPIError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+		assert.Equal(t, "/random/endpoint", apiErr.Endpoint)
+	}
+}
+
+func Test_Client_APIError_InvalidBody(t *testing.T) {
+	ctx := auth.WithPAT(context.Background(), "username", "password")
+
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusBadGateway,
+		Body:       io.NopCloser(bytes.NewBufferString(`<html></html>`)),
+	}, nil)
+
+	webService := &livechatClient{
+		httpClient: httpClient,
+		url:        "http://lorem.pl",
+	}
+
+	_, err := webService.sendRequest(ctx, &exampleRequest{Data: "random data"}, nil)
+
+	apiErr, ok := AsAPIError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	}
+}
+
+func Test_Client_APIError_Message(t *testing.T) {
+	ctx := auth.WithPAT(context.Background(), "username", "password")
+
+	tests := []struct {
+		body          string
+		offline       bool
+		alreadyInChat bool
+	}{
+		{body: `{"error": {"type": "validation", "message": "Agent is offline."}}`, offline: true},
+		{body: `{"error": {"type": "validation", "message": "One or more of requested agents are already present in the chat"}}`, alreadyInChat: true},
+		{body: `{"error": {"type": "validation", "message": "Chat is inactive"}}`},
+		{body: `{"error": {"type": "internal", "message": "Agent is offline."}}`},
+	}
+
+	for _, test := range tests {
+		httpClient := new(mocks.Client)
+		httpClient.On("Do", mock.Anything).Return(&http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       io.NopCloser(bytes.NewBufferString(test.body)),
+		}, nil)
+
+		webService := &livechatClient{httpClient: httpClient, url: "http://lorem.pl"}
+		_, err := webService.TransferChat(ctx, &livechat.TransferChatRequest{ID: "chat"})
+
+		assert.Equal(t, test.offline, errors.Is(err, ErrAgentOffline), test.body)
+		assert.Equal(t, test.alreadyInChat, errors.Is(err, ErrAgentAlreadyInChat), test.body)
+	}
+}
+
+func Test_Client_Retry_Idempotent(t *testing.T) {
+	ctx := auth.WithPAT(context.Background(), "username", "password")
+
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.Anything).Once().Return(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"error": {"type": "service_unavailable", "message": "Try later"}}`)),
+	}, nil)
+	httpClient.On("Do", mock.Anything).Once().Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString(`[]`)),
+	}, nil)
+
+	webService := helperCreateRetryingClient(t, httpClient)
+	endpoint := (&livechat.ListAgentsForTransferRequest{}).Endpoint()
+	failed := helperRequestsObserved(t, endpoint, "503")
+
+	_, err := webService.ListAgentsForTransfer(ctx, &livechat.ListAgentsForTransferRequest{ChatID: "chat"})
+	assert.NoError(t, err)
+	httpClient.AssertNumberOfCalls(t, "Do", 2)
+	assert.Equal(t, failed+1, helperRequestsObserved(t, endpoint, "503"))
+}
+
+func Test_Client_Retry_NotIdempotent(t *testing.T) {
+	ctx := auth.WithPAT(context.Background(), "username", "password")
+
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"error": {"type": "internal", "message": "Internal error"}}`)),
+	}, nil)
+
+	webService := helperCreateRetryingClient(t, httpClient)
+
+	_, err := webService.TransferChat(ctx, &livechat.TransferChatRequest{ID: "chat"})
+	assert.True(t, errors.Is(err, ErrInternal))
+	httpClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func Test_Client_Retry_TooManyRequests(t *testing.T) {
+	ctx := auth.WithPAT(context.Background(), "username", "password")
+
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.Anything).Once().Return(&http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"1"}},
+		Body:       io.NopCloser(bytes.NewBufferString(`{"error": {"type": "too_many_requests", "message": "Slow down"}}`)),
+	}, nil)
+	httpClient.On("Do", mock.Anything).Once().Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+	}, nil)
+
+	webService := helperCreateRetryingClient(t, httpClient)
+	delays := []time.Duration{}
+	webService.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	_, err := webService.TransferChat(ctx, &livechat.TransferChatRequest{ID: "chat"})
+	assert.NoError(t, err)
+	// "Retry-After" is capped by MaxDelay of the client
+	assert.Equal(t, []time.Duration{10 * time.Millisecond}, delays)
+	httpClient.AssertNumberOfCalls(t, "Do", 2)
+}
+
+func Test_Client_Retry_GivesUp(t *testing.T) {
+	ctx := auth.WithPAT(context.Background(), "username", "password")
+
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.Anything).Return(nil, errors.New("connection reset by peer"))
+
+	webService := helperCreateRetryingClient(t, httpClient)
+
+	_, err := webService.GetChat(ctx, &livechat.GetChatRequest{ChatID: "chat"})
+	assert.Error(t, err)
+	httpClient.AssertNumberOfCalls(t, "Do", 3)
+}
+
+func Test_Client_RateLimit(t *testing.T) {
+	ctx := auth.WithPAT(context.Background(), "username", "password")
+	ctx = auth.WithLicenseID(ctx, livechat.LicenseID(1234))
+
+	httpClient := new(mocks.Client)
+	httpClient.On("Do", mock.Anything).Return(func(*http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+		}
+	}, nil)
+
+	webService := &livechatClient{
+		httpClient: httpClient,
+		url:        "http://lorem.pl",
+		limiter:    newLimiter(RateLimit{RequestsPerSecond: 10, Burst: 1}),
+	}
+
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := webService.SendEvent(ctx, livechat.BuildMessage("chat", "message"))
+		assert.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, int64(time.Since(started)), int64(150*time.Millisecond))
+
+	// other license has its own bucket
+	started = time.Now()
+	_, err := webService.SendEvent(auth.WithLicenseID(ctx, livechat.LicenseID(5678)), livechat.BuildMessage("chat", "message"))
+	assert.NoError(t, err)
+	assert.Less(t, int64(time.Since(started)), int64(50*time.Millisecond))
+}
+
+func helperCreateRetryingClient(t *testing.T, httpClient *mocks.Client) *livechatClient {
+	t.Helper()
+
+	return &livechatClient{
+		httpClient: httpClient,
+		url:        "http://lorem.pl",
+		retry: RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    10 * time.Millisecond,
+		},
+	}
+}
+
+// helperRequestsObserved returns number of requests observed by duration
+// metric so far, metric is global so tests compare it before and after.
+func helperRequestsObserved(t *testing.T, endpoint, code string) uint64 {
+	t.Helper()
+
+	var observed dto.Metric
+	if err := metrics.RequestDuration.WithLabelValues(endpoint, code).(prometheus.Histogram).Write(&observed); err != nil {
+		t.Fatalf("cannot read metric: %s", err)
+	}
+	return observed.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIError is an error returned by LiveChat API.
+type APIError struct {
+	// StatusCode is a HTTP status code of response (empty for RTM API).
+	StatusCode int
+	// Type is a type of error, as documented by LiveChat.
+	Type    string
+	Message string
+	// Endpoint is an endpoint (or RTM action) which has been requested.
+	Endpoint string
+	// RetryAfter is a delay requested by LiveChat with "Retry-After" header.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s: %s (type %s)", e.Endpoint, e.Message, e.Type)
+	}
+	return fmt.Sprintf("%s: %s (type %s, status %d)", e.Endpoint, e.Message, e.Type, e.StatusCode)
+}
+
+// Is reports whether error is of the same type as target, so
+// errors.Is(err, web.ErrNotFound) works for any not found error.
+// Target with message matches only errors whose message contains it.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok || t.Type == "" || t.Type != e.Type {
+		return false
+	}
+	return t.Message == "" || strings.Contains(strings.ToLower(e.Message), t.Message)
+}
+
+// Sentinels of the most common error types.
+var (
+	ErrAuthentication     = &APIError{Type: "authentication"}
+	ErrAuthorization      = &APIError{Type: "authorization"}
+	ErrValidation         = &APIError{Type: "validation"}
+	ErrNotFound           = &APIError{Type: "not_found"}
+	ErrTooManyRequests    = &APIError{Type: "too_many_requests"}
+	ErrInternal           = &APIError{Type: "internal"}
+	ErrServiceUnavailable = &APIError{Type: "service_unavailable"}
+	ErrRequestTimeout     = &APIError{Type: "request_timeout"}
+)
+
+// Sentinels of errors which LiveChat reports only as "validation", so
+// the (lower-cased) message is the only way to tell them apart.
+var (
+	// ErrAgentOffline is returned when chat is transferred to offline agent.
+	ErrAgentOffline = &APIError{Type: "validation", Message: "agent is offline"}
+	// ErrAgentAlreadyInChat is returned when chat is transferred to agent
+	// who is already present in that chat.
+	ErrAgentAlreadyInChat = &APIError{Type: "validation", Message: "already present in the chat"}
+)
+
+// AsAPIError returns LiveChat API error wrapped in err, if any.
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"context"
+	"sync"
+
+	"github.com/livechat/onboarding/livechat"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit defines token bucket which every license has to take a token
+// from before sending request. Disabled if RequestsPerSecond is zero or negative.
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+var DefaultRateLimit = RateLimit{
+	RequestsPerSecond: 10,
+	Burst:             20,
+}
+
+type limiter struct {
+	limit RateLimit
+
+	mu      sync.Mutex
+	buckets map[livechat.LicenseID]*rate.Limiter
+}
+
+func newLimiter(limit RateLimit) *limiter {
+	return &limiter{
+		limit:   limit,
+		buckets: make(map[livechat.LicenseID]*rate.Limiter),
+	}
+}
+
+// Wait blocks until license is allowed to send the request.
+// Requests without license share the same bucket.
+func (l *limiter) Wait(ctx context.Context, id livechat.LicenseID) error {
+	if l == nil || l.limit.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	return l.bucket(id).Wait(ctx)
+}
+
+func (l *limiter) bucket(id livechat.LicenseID) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[id]
+	if !ok {
+		burst := l.limit.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		bucket = rate.NewLimiter(rate.Limit(l.limit.RequestsPerSecond), burst)
+		l.buckets[id] = bucket
+	}
+	return bucket
+}
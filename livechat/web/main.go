@@ -27,9 +27,24 @@ type LivechatRequests interface {
 	RemoveUserFromChat(context.Context, *livechat.RemoveUserFromChatRequest) (*livechat.RemoveUserFromChatResponse, error)
 }
 
+type Config struct {
+	Retry     RetryPolicy
+	RateLimit RateLimit
+}
+
+// New returns client with default retry policy and rate limit.
 func New(client livechat.Client, url string) LivechatRequests {
+	return NewWithConfig(client, url, &Config{
+		Retry:     DefaultRetryPolicy,
+		RateLimit: DefaultRateLimit,
+	})
+}
+
+func NewWithConfig(client livechat.Client, url string, cfg *Config) LivechatRequests {
 	return &livechatClient{
 		httpClient: client,
 		url:        url,
+		retry:      cfg.Retry,
+		limiter:    newLimiter(cfg.RateLimit),
 	}
 }
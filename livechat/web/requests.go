@@ -58,6 +58,10 @@ func (c *livechatClient) TransferChat(ctx context.Context, payload *livechat.Tra
 }
 
 func (c *livechatClient) SendEvent(ctx context.Context, payload *livechat.Event) (*livechat.SendEventResponse, error) {
+	if err := payload.Validate(); err != nil {
+		return nil, fmt.Errorf("send_event action: %w", err)
+	}
+
 	var body livechat.SendEventResponse
 	_, err := c.sendRequest(ctx, payload, &body)
 	if err != nil {
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy defines how failed requests are repeated.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first one.
+	// Requests are not repeated if lower than 2.
+	MaxAttempts int
+	// BaseDelay is the delay before second attempt, it doubles with every
+	// following attempt (up to MaxDelay). Actual delay is randomized
+	// between zero and that value.
+	BaseDelay time.Duration
+	// MaxDelay limits delay requested with "Retry-After" header too.
+	MaxDelay time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// delay returns how long to wait before the next attempt, or false if
+// request should not be repeated. Rate limited requests have not been
+// processed by LiveChat, so they are repeated even if not idempotent.
+func (p *RetryPolicy) delay(attempt int, idempotent bool, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+
+	apiErr, ok := AsAPIError(err)
+	switch {
+	case ok && apiErr.StatusCode == http.StatusTooManyRequests:
+		if apiErr.RetryAfter > 0 {
+			return p.retryAfter(apiErr.RetryAfter), true
+		}
+	case ok && apiErr.StatusCode >= http.StatusInternalServerError:
+		if !idempotent {
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 {
+			return p.retryAfter(apiErr.RetryAfter), true
+		}
+	case ok:
+		return 0, false
+	case !idempotent:
+		return 0, false
+	}
+
+	return p.backoff(attempt), true
+}
+
+// retryAfter caps delay requested by LiveChat, so a huge "Retry-After"
+// does not block the caller for good.
+func (p *RetryPolicy) retryAfter(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.BaseDelay
+	for i := 1; i < attempt && backoff < p.MaxDelay; i++ {
+		backoff *= 2
+	}
+	if p.MaxDelay > 0 && backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// parseRetryAfter reads "Retry-After" header given either
+// in seconds or as HTTP date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
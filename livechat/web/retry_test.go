@@ -0,0 +1,65 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 10*time.Second, parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func Test_RetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	for attempt := 1; attempt < 10; attempt++ {
+		delay := policy.backoff(attempt)
+		assert.GreaterOrEqual(t, int64(delay), int64(0))
+		assert.Less(t, int64(delay), int64(300*time.Millisecond))
+	}
+}
+
+func Test_RetryPolicy_Delay(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+
+	_, retry := policy.delay(1, false, &APIError{StatusCode: http.StatusTooManyRequests})
+	assert.True(t, retry)
+	_, retry = policy.delay(1, false, &APIError{StatusCode: http.StatusBadGateway})
+	assert.False(t, retry)
+	_, retry = policy.delay(1, true, &APIError{StatusCode: http.StatusBadGateway})
+	assert.True(t, retry)
+	_, retry = policy.delay(1, true, &APIError{StatusCode: http.StatusBadRequest})
+	assert.False(t, retry)
+	_, retry = policy.delay(1, true, context.Canceled)
+	assert.False(t, retry)
+	_, retry = policy.delay(1, true, errors.New("connection refused"))
+	assert.True(t, retry)
+	_, retry = policy.delay(2, true, errors.New("connection refused"))
+	assert.False(t, retry)
+}
+
+func Test_RetryPolicy_Delay_RetryAfter(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second}
+
+	delay, retry := policy.delay(1, false, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Second})
+	assert.True(t, retry)
+	assert.Equal(t, 3*time.Second, delay)
+
+	delay, retry = policy.delay(1, false, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 24 * time.Hour})
+	assert.True(t, retry)
+	assert.Equal(t, 5*time.Second, delay)
+
+	delay, retry = policy.delay(1, true, &APIError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 24 * time.Hour})
+	assert.True(t, retry)
+	assert.Equal(t, 5*time.Second, delay)
+}
@@ -3,27 +3,42 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/flow"
+	"github.com/livechat/onboarding/bot/handoff"
+	"github.com/livechat/onboarding/bot/queue"
+	"github.com/livechat/onboarding/bot/routing"
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/auth"
+	"github.com/livechat/onboarding/livechat/web"
+	"github.com/livechat/onboarding/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
-func init() {
-	log.SetLevel(log.DebugLevel)
-}
-
 func main() {
 	// CONFIGURATION
-	cfg, err := LoadConfigFile("config.json")
+	args, environ := os.Args[1:], os.Environ()
+	cfg, err := LoadConfigSources(args, environ)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.WithError(err).Panic("Cannot load configuration for app")
 	}
+	log.SetLevel(cfg.Log.SelectLevel())
+
+	reload := newReloader(cfg.path, func() (*config, error) {
+		return LoadConfigSources(args, environ)
+	})
 
 	httpClient := &http.Client{Timeout: 5 * time.Second}
 
@@ -36,14 +51,39 @@ func main() {
 	router.Use(middleware.RequestLogger(&logrusFormatter{logger: log.StandardLogger()}))
 	router.Use(middleware.Recoverer)
 
-	botManager := StartWebhooks(cfg, &appMethodConfig{
+	checks := newReadiness()
+	methodConfig := &appMethodConfig{
+		ctx:        ctx,
 		httpClient: httpClient,
 		router:     router,
-	})
+		readiness:  checks,
+		reloader:   reload,
+	}
+	botManager := StartMethod(cfg, methodConfig)
+	reload.OnReload(reloadLogLevel)
+	go reload.Watch(ctx, configPollInterval)
+
+	checks.Add("token", checkToken(botManager, cfg.Health.Licenses))
+	checks.Add("licenses", checkLicenses(botManager, cfg.Health.Licenses))
+	checks.Add("livechat_api", checkLivechatAPI(httpClient, cfg.URL.HTTP))
+	router.Get("/healthz", handleHealthz)
+	router.Get("/readyz", checks.Handler)
+
+	srv := newServer(&cfg.Server, router)
 
 	Shutdown(ctx, cancel, func() {
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelDrain()
+
+		srv.Drain(drainCtx)
+		for _, drain := range methodConfig.drain {
+			drain(drainCtx)
+		}
 		httpClient.CloseIdleConnections()
-		botManager.Destroy(ctx)
+
+		destroyCtx, cancelDestroy := context.WithTimeout(ctx, destroyTimeout)
+		defer cancelDestroy()
+		botManager.Destroy(destroyCtx)
 	})
 
 	router.Get("/auth", func(w http.ResponseWriter, r *http.Request) {
@@ -102,10 +142,20 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	metrics.Registry.MustRegister(&chatsCollector{admin: botManager})
+	router.Handle("/metrics", metrics.Handler())
+
+	if cfg.Admin != nil {
+		router.Mount("/admin", AdminRouter(cfg.Admin, botManager))
+	}
+
 	log.Print("Starting application")
-	if err := http.ListenAndServe(":8081", router); err != nil {
+	if err := srv.Serve(); err != nil {
 		log.Panicf("Something happened during HTTP request: %s", err)
 	}
+
+	// Server has been drained, wait until bots are destroyed too.
+	<-ctx.Done()
 }
 
 func sendError(w http.ResponseWriter, err error) {
@@ -121,6 +171,58 @@ func sendError(w http.ResponseWriter, err error) {
 }
 
 type appMethodConfig struct {
+	ctx        context.Context
 	httpClient *http.Client
 	router     *chi.Mux
+	readiness  *readiness
+	reloader   *reloader
+	// drain is called on shutdown, after HTTP server stops accepting requests.
+	// Every drain shares the deadline of ctx with the server.
+	drain []func(ctx context.Context)
+}
+
+func newSender(cfg *config, lcHTTP web.LivechatRequests) bot.ReloadableSender {
+	conversation, err := flow.New(cfg.Bot.SelectFlow())
+	if err != nil {
+		log.WithError(err).Panic("Cannot load conversation flow")
+	}
+
+	transfer, err := handoff.New(lcHTTP, cfg.Bot.Handoff.Handoff())
+	if err != nil {
+		log.WithError(err).Panic("Cannot configure human handoff")
+	}
+
+	return bot.NewSender(lcHTTP, cfg.Credentials.AuthorID, conversation, transfer)
+}
+
+func newRouter(cfg *config) *routing.Router {
+	router, err := routing.New(cfg.Bot.Routing)
+	if err != nil {
+		log.WithError(err).Panic("Cannot configure routing of chats")
+	}
+
+	return router
+}
+
+// newQueue creates queue of pushes which is drained on shutdown.
+func newQueue(cfg *config, config *appMethodConfig) *queue.Queue {
+	jobs := queue.New(config.ctx, cfg.Queue.Queue())
+	config.drain = append(config.drain, func(ctx context.Context) {
+		if err := jobs.Drain(ctx); err != nil {
+			log.WithError(err).Warn("Push queue has not been drained")
+		}
+	})
+
+	return jobs
+}
+
+func StartMethod(cfg *config, config *appMethodConfig) bot.BotManager {
+	switch cfg.SelectMethod() {
+	case rtmMethod:
+		log.Debug("Selected RTM method")
+		return StartRTM(cfg, config)
+	default:
+		log.Debug("Selected webhooks method")
+		return StartWebhooks(cfg, config)
+	}
 }
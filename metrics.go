@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var botChatsDesc = prometheus.NewDesc(
+	"onboarding_bot_chats",
+	"Chats currently assigned to bot.",
+	[]string{"license_id", "bot_id"}, nil,
+)
+
+// chatsCollector reads chats assigned to bots when metrics are scraped,
+// so the value is always in sync with the state of installed apps.
+type chatsCollector struct {
+	admin bot.Admin
+}
+
+func (c *chatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- botChatsDesc
+}
+
+func (c *chatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, license := range c.admin.Licenses() {
+		licenseID := strconv.Itoa(int(license.ID))
+		for _, agent := range license.Bots {
+			ch <- prometheus.MustNewConstMetric(botChatsDesc, prometheus.GaugeValue, float64(len(agent.Chats)), licenseID, string(agent.ID))
+		}
+	}
+}
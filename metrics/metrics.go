@@ -0,0 +1,93 @@
+// Package metrics defines Prometheus metrics of bot activity
+// and requests sent to LiveChat API.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "onboarding"
+
+var (
+	PushesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pushes_received_total",
+		Help:      "Pushes (webhooks or RTM pushes) received from LiveChat.",
+	}, []string{"action", "license_id"})
+
+	RedirectErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirect_errors_total",
+		Help:      "Pushes which could not be handled by bot.",
+	}, []string{"action"})
+
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "livechat_request_duration_seconds",
+		Help:      "Duration of requests sent to LiveChat Web API (every attempt separately).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "status"})
+
+	Handoffs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handoffs_total",
+		Help:      "Chats which bot tried to hand off to human, by result.",
+	}, []string{"strategy", "result"})
+
+	TransferAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_attempts_total",
+		Help:      "Attempts to transfer chat to agent or group during handoff.",
+	}, []string{"strategy", "result"})
+
+	QueuedJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queued_jobs",
+		Help:      "Pushes waiting in queue or being handled by workers.",
+	}, []string{"license_id"})
+
+	RejectedPushes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rejected_pushes_total",
+		Help:      "Pushes rejected because queue was full or closed.",
+	}, []string{"license_id"})
+
+	DuplicatedPushes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicated_pushes_total",
+		Help:      "Redelivered webhooks which have been ignored.",
+	}, []string{"action"})
+
+	ChatsAtCapacity = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chats_at_capacity_total",
+		Help:      "Incoming chats left for humans because every bot was at capacity.",
+	}, []string{"license_id"})
+)
+
+// Registry keeps every metric of the app.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		PushesReceived,
+		RedirectErrors,
+		RequestDuration,
+		Handoffs,
+		TransferAttempts,
+		QueuedJobs,
+		RejectedPushes,
+		DuplicatedPushes,
+		ChatsAtCapacity,
+	)
+}
+
+// Handler exposes metrics in Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
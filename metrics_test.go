@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type chatsAdmin struct {
+	bot.Admin
+}
+
+func (a *chatsAdmin) Licenses() []*bot.LicenseInfo {
+	return []*bot.LicenseInfo{{
+		ID: 1234,
+		Bots: []*bot.BotInfo{
+			{ID: "bot_1", Chats: []livechat.ChatID{"chat_1", "chat_2"}},
+			{ID: "bot_2", Chats: []livechat.ChatID{}},
+		},
+	}}
+}
+
+func Test_ChatsCollector(t *testing.T) {
+	expected := `
+# HELP onboarding_bot_chats Chats currently assigned to bot.
+# TYPE onboarding_bot_chats gauge
+onboarding_bot_chats{bot_id="bot_1",license_id="1234"} 2
+onboarding_bot_chats{bot_id="bot_2",license_id="1234"} 0
+`
+	err := testutil.CollectAndCompare(&chatsCollector{admin: &chatsAdmin{}}, strings.NewReader(expected))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/flow"
+	"github.com/livechat/onboarding/bot/handoff"
+	"github.com/livechat/onboarding/bot/routing"
+	"github.com/livechat/onboarding/livechat/web"
+	log "github.com/sirupsen/logrus"
+)
+
+const configPollInterval = 5 * time.Second
+
+// reloader reads configuration again when its file changes or process
+// receives SIGHUP and hands it over to registered hooks. Only settings
+// of hooks are applied, the rest requires restart.
+type reloader struct {
+	load func() (*config, error)
+	path string
+
+	mu      sync.Mutex
+	hooks   []reloadHook
+	modTime time.Time
+}
+
+// reloadHook prepares its settings of configuration and returns function
+// which applies them. It must not change anything by itself, so nothing is
+// applied unless every hook accepts the configuration.
+type reloadHook func(*config) (apply func(), err error)
+
+func newReloader(path string, load func() (*config, error)) *reloader {
+	r := &reloader{load: load, path: path}
+	r.modTime, _ = r.stat()
+	return r
+}
+
+// OnReload registers hook called with every valid configuration.
+func (r *reloader) OnReload(hook reloadHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+// Reload loads and validates configuration, then applies it. Invalid
+// configuration is rejected and the current one is kept.
+func (r *reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	applies := make([]func(), 0, len(r.hooks))
+	for _, hook := range r.hooks {
+		apply, err := hook(cfg)
+		if err != nil {
+			return err
+		}
+		applies = append(applies, apply)
+	}
+	for _, apply := range applies {
+		apply()
+	}
+
+	return nil
+}
+
+// Watch reloads configuration until ctx is done.
+func (r *reloader) Watch(ctx context.Context, interval time.Duration) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			r.reload("signal")
+		case <-ticker.C:
+			if r.changed() {
+				r.reload("file")
+			}
+		}
+	}
+}
+
+func (r *reloader) reload(trigger string) {
+	logEntry := log.WithField("path", r.path).WithField("trigger", trigger)
+	if err := r.Reload(); err != nil {
+		logEntry.WithError(err).Error("Rejected new configuration, the current one is kept")
+		return
+	}
+	logEntry.Info("Configuration reloaded")
+}
+
+func (r *reloader) changed() bool {
+	modTime, err := r.stat()
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if modTime.Equal(r.modTime) {
+		return false
+	}
+	r.modTime = modTime
+	return true
+}
+
+func (r *reloader) stat() (time.Time, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// reloadSender swaps conversation flow and handoff of sender.
+func reloadSender(sender bot.ReloadableSender, lcHTTP web.LivechatRequests) reloadHook {
+	return func(cfg *config) (func(), error) {
+		conversation, err := flow.New(cfg.Bot.SelectFlow())
+		if err != nil {
+			return nil, err
+		}
+		transfer, err := handoff.New(lcHTTP, cfg.Bot.Handoff.Handoff())
+		if err != nil {
+			return nil, err
+		}
+		return func() { sender.Reload(conversation, transfer) }, nil
+	}
+}
+
+// reloadRouter swaps routing rules of chats.
+func reloadRouter(router *routing.Router) reloadHook {
+	return func(cfg *config) (func(), error) {
+		next, err := routing.New(cfg.Bot.Routing)
+		if err != nil {
+			return nil, err
+		}
+		return func() { router.Adopt(next) }, nil
+	}
+}
+
+// reloadLogLevel changes level of the standard logger.
+func reloadLogLevel(cfg *config) (func(), error) {
+	return func() { log.SetLevel(cfg.Log.SelectLevel()) }, nil
+}
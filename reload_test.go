@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const reloadBaseConfig = `
+	"auth": {"username": "u", "password": "p"},
+	"credentials": {"client_id": "c", "client_secret": "s", "author_id": "a"},
+	"url": {"http": "h", "ws": "w", "local": "l"}`
+
+func Test_Reloader_RejectsInvalidConfig(t *testing.T) {
+	path := helperWriteConfig(t, `{`+reloadBaseConfig+`, "log": {"level": "info"}}`)
+	reload := helperCreateReloader(path)
+
+	levels := []string{}
+	reload.OnReload(func(cfg *config) (func(), error) {
+		return func() { levels = append(levels, cfg.Log.Level) }, nil
+	})
+
+	assert.NoError(t, reload.Reload())
+
+	helperRewriteConfig(t, path, `{`+reloadBaseConfig+`, "log": {"level": "loud"}}`)
+	assert.Error(t, reload.Reload())
+
+	helperRewriteConfig(t, path, `{`+reloadBaseConfig+`, "bot": {"handoff": {"strategy": "group"}}}`)
+	assert.Error(t, reload.Reload())
+
+	assert.Equal(t, []string{"info"}, levels)
+}
+
+func Test_Reloader_AppliesAllOrNothing(t *testing.T) {
+	path := helperWriteConfig(t, `{`+reloadBaseConfig+`, "log": {"level": "info"}}`)
+	reload := helperCreateReloader(path)
+
+	levels := []string{}
+	reload.OnReload(func(cfg *config) (func(), error) {
+		return func() { levels = append(levels, cfg.Log.Level) }, nil
+	})
+	reload.OnReload(func(cfg *config) (func(), error) {
+		if cfg.Log.Level == "error" {
+			return nil, errors.New("rejected by the second hook")
+		}
+		return func() {}, nil
+	})
+
+	assert.NoError(t, reload.Reload())
+
+	helperRewriteConfig(t, path, `{`+reloadBaseConfig+`, "log": {"level": "error"}}`)
+	assert.Error(t, reload.Reload())
+
+	assert.Equal(t, []string{"info"}, levels)
+}
+
+func Test_Reloader_WatchesFile(t *testing.T) {
+	defer log.SetLevel(log.StandardLogger().GetLevel())
+
+	path := helperWriteConfig(t, `{`+reloadBaseConfig+`, "log": {"level": "info"}}`)
+	reload := helperCreateReloader(path)
+	reload.OnReload(reloadLogLevel)
+	assert.False(t, reload.changed())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reload.Watch(ctx, 10*time.Millisecond)
+
+	helperRewriteConfig(t, path, `{`+reloadBaseConfig+`, "log": {"level": "warn"}}`)
+	assert.Eventually(t, func() bool {
+		return log.GetLevel() == log.WarnLevel
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_LogConfig_SelectLevel(t *testing.T) {
+	assert.Equal(t, log.DebugLevel, (&logConfig{}).SelectLevel())
+	assert.Equal(t, log.ErrorLevel, (&logConfig{Level: "error"}).SelectLevel())
+}
+
+func helperCreateReloader(path string) *reloader {
+	return newReloader(path, func() (*config, error) {
+		return LoadConfigSources([]string{"-config", path}, nil)
+	})
+}
+
+// helperRewriteConfig replaces content of config and moves its
+// modification time, so the change is noticed on coarse filesystems.
+func helperRewriteConfig(t *testing.T, path string, content string) {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("cannot stat config: %s", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("cannot write config: %s", err)
+	}
+	modTime := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("cannot touch config: %s", err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAddress           = ":8081"
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 10 * time.Second
+	defaultWriteTimeout      = 15 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+	defaultMaxBodyBytes      = 1 << 20
+
+	// shutdownTimeout is shared by draining of server and queue, bots are
+	// destroyed within destroyTimeout afterwards. Together they have to be
+	// shorter than forcedShutdownTimeout.
+	shutdownTimeout = 6 * time.Second
+	destroyTimeout  = 3 * time.Second
+)
+
+type server struct {
+	*http.Server
+	tlsCert string
+	tlsKey  string
+}
+
+func newServer(cfg *serverConfig, handler http.Handler) *server {
+	return &server{
+		Server: &http.Server{
+			Addr:              cfg.SelectAddress(),
+			Handler:           limitBody(cfg.SelectMaxBodyBytes(), handler),
+			ReadHeaderTimeout: selectDuration(cfg.ReadHeaderTimeoutMs, defaultReadHeaderTimeout),
+			ReadTimeout:       selectDuration(cfg.ReadTimeoutMs, defaultReadTimeout),
+			WriteTimeout:      selectDuration(cfg.WriteTimeoutMs, defaultWriteTimeout),
+			IdleTimeout:       selectDuration(cfg.IdleTimeoutMs, defaultIdleTimeout),
+		},
+		tlsCert: cfg.TLSCert,
+		tlsKey:  cfg.TLSKey,
+	}
+}
+
+// Serve blocks until server is closed. Closing server
+// with Shutdown is not considered an error.
+func (s *server) Serve() error {
+	var err error
+	if s.tlsCert != "" {
+		log.WithField("address", s.Addr).Info("Listening with TLS")
+		err = s.ListenAndServeTLS(s.tlsCert, s.tlsKey)
+	} else {
+		log.WithField("address", s.Addr).Info("Listening")
+		err = s.ListenAndServe()
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Drain stops accepting new connections and waits for active requests
+// until ctx is done.
+func (s *server) Drain(ctx context.Context) {
+	if err := s.Shutdown(ctx); err != nil {
+		log.WithError(err).Warn("HTTP server has not been drained")
+	}
+}
+
+func limitBody(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func selectDuration(ms int, fallback time.Duration) time.Duration {
+	if ms == 0 {
+		return fallback
+	}
+	return time.Duration(ms) * time.Millisecond
+}
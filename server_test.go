@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewServer_Defaults(t *testing.T) {
+	srv := newServer(&serverConfig{}, http.NotFoundHandler())
+
+	assert.Equal(t, defaultAddress, srv.Addr)
+	assert.Equal(t, defaultReadHeaderTimeout, srv.ReadHeaderTimeout)
+	assert.Equal(t, defaultIdleTimeout, srv.IdleTimeout)
+}
+
+func Test_NewServer_Config(t *testing.T) {
+	srv := newServer(&serverConfig{Address: ":9090", WriteTimeoutMs: 1500}, http.NotFoundHandler())
+
+	assert.Equal(t, ":9090", srv.Addr)
+	assert.Equal(t, 1500*time.Millisecond, srv.WriteTimeout)
+	assert.Equal(t, defaultReadTimeout, srv.ReadTimeout)
+}
+
+func Test_LimitBody(t *testing.T) {
+	handler := limitBody(8, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("short")))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too long body")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func Test_Server_Drain(t *testing.T) {
+	srv := newServer(&serverConfig{Address: "127.0.0.1:0"}, http.NotFoundHandler())
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve() }()
+	time.Sleep(50 * time.Millisecond)
+
+	srv.Drain(context.Background())
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatalf("server has not been stopped")
+	}
+}
+
+func Test_Server_ShutdownTimeouts(t *testing.T) {
+	assert.Less(t, int64(shutdownTimeout+destroyTimeout), int64(forcedShutdownTimeout))
+}
@@ -9,6 +9,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// forcedShutdownTimeout is the time given to extra before the app exits anyway.
+const forcedShutdownTimeout = 10 * time.Second
+
 func Shutdown(ctx context.Context, cancel context.CancelFunc, extra func()) {
 	osSignals := make(chan os.Signal, 1)
 	signal.Notify(osSignals, os.Interrupt)
@@ -25,7 +28,7 @@ func Shutdown(ctx context.Context, cancel context.CancelFunc, extra func()) {
 
 		closeApp := func(ctx context.Context, withCancel bool) {
 			go func() {
-				<-time.After(10 * time.Second)
+				<-time.After(forcedShutdownTimeout)
 				log.WithContext(ctx).Fatalf("Forced shutdown")
 			}()
 
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/livechat/onboarding/bot"
+	"github.com/livechat/onboarding/bot/bot_rtm"
+	"github.com/livechat/onboarding/livechat/web"
+)
+
+func StartRTM(cfg *config, config *appMethodConfig) bot.BotManager {
+	// LIVECHAT SERVICES
+	lcHTTP := web.NewWithConfig(config.httpClient, cfg.URL.HTTP, cfg.API.Client())
+	sender, router := newSender(cfg, lcHTTP), newRouter(cfg)
+	config.reloader.OnReload(reloadSender(sender, lcHTTP))
+	config.reloader.OnReload(reloadRouter(router))
+
+	return bot_rtm.New(lcHTTP, sender, router, cfg.Bot.Capacity.Capacity(), newQueue(cfg, config), cfg.URL.WS)
+}
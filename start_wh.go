@@ -1,49 +1,165 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/livechat/onboarding/bot"
 	"github.com/livechat/onboarding/bot/bot_webhooks"
+	"github.com/livechat/onboarding/bot/dedup"
+	"github.com/livechat/onboarding/bot/queue"
+	"github.com/livechat/onboarding/bot/store"
 	"github.com/livechat/onboarding/livechat"
 	"github.com/livechat/onboarding/livechat/auth"
 	"github.com/livechat/onboarding/livechat/web"
+	"github.com/livechat/onboarding/metrics"
+	log "github.com/sirupsen/logrus"
 )
 
 func StartWebhooks(cfg *config, config *appMethodConfig) bot.BotManager {
 	// LIVECHAT SERVICES
-	lcHTTP := web.New(config.httpClient, cfg.URL.HTTP)
-	bot := bot_webhooks.New(lcHTTP, cfg.URL.Local, cfg.Credentials.AuthorID)
-
-	config.router.Post("/webhooks/incoming_event", handleIncomingMsg(bot, cfg, func() livechat.Push {
-		return &livechat.PushIncomingMessage{}
-	}))
-	config.router.Post("/webhooks/incoming_chat", handleIncomingMsg(bot, cfg, func() livechat.Push {
-		return &livechat.PushIncomingChat{}
-	}))
-	config.router.Post("/webhooks/user_added_to_chat", handleIncomingMsg(bot, cfg, func() livechat.Push {
-		return &livechat.PushUserAddedToChat{}
-	}))
-
-	return bot
+	lcHTTP := web.NewWithConfig(config.httpClient, cfg.URL.HTTP, cfg.API.Client())
+	botStore, err := store.New(cfg.Store.Path)
+	if err != nil {
+		log.WithError(err).Panic("Cannot open store")
+	}
+
+	config.readiness.Add("store", checkPing(botStore))
+
+	sender, router := newSender(cfg, lcHTTP), newRouter(cfg)
+	config.reloader.OnReload(reloadSender(sender, lcHTTP))
+	config.reloader.OnReload(reloadRouter(router))
+
+	bot := bot_webhooks.New(lcHTTP, sender, router, cfg.Bot.Capacity.Capacity(), botStore, cfg.URL.Local)
+	credentials := &auth.AuthorizeCredentials{ClientID: cfg.Credentials.ClientID, Secret: cfg.Credentials.Secret}
+	if err := bot.Restore(config.ctx, config.httpClient, credentials); err != nil {
+		log.WithError(err).Panic("Cannot restore apps")
+	}
+
+	seen := cfg.Dedup.Store()
+	jobs := newQueue(cfg, config)
+
+	config.router.Group(func(r chi.Router) {
+		r.Use(verifySecretKey(bot))
+
+		r.Post("/webhooks/incoming_event", handleIncomingMsg(bot, jobs, seen, func() livechat.Push {
+			return &livechat.PushIncomingMessage{}
+		}))
+		r.Post("/webhooks/incoming_chat", handleIncomingMsg(bot, jobs, seen, func() livechat.Push {
+			return &livechat.PushIncomingChat{}
+		}))
+		r.Post("/webhooks/user_added_to_chat", handleIncomingMsg(bot, jobs, seen, func() livechat.Push {
+			return &livechat.PushUserAddedToChat{}
+		}))
+		r.Post("/webhooks/incoming_rich_message_postback", handleIncomingMsg(bot, jobs, seen, func() livechat.Push {
+			return &livechat.PushIncomingRichMessagePostback{}
+		}))
+		r.Post("/webhooks/chat_deactivated", handleIncomingMsg(bot, jobs, seen, func() livechat.Push {
+			return &livechat.PushChatDeactivated{}
+		}))
+		r.Post("/webhooks/user_removed_from_chat", handleIncomingMsg(bot, jobs, seen, func() livechat.Push {
+			return &livechat.PushUserRemovedFromChat{}
+		}))
+		r.Post("/webhooks/chat_transferred", handleIncomingMsg(bot, jobs, seen, func() livechat.Push {
+			return &livechat.PushChatTransferred{}
+		}))
+	})
+
+	return &queuedManager{Manager: bot, jobs: jobs}
+}
+
+// queuedManager stops queue workers of license once it is uninstalled.
+type queuedManager struct {
+	bot_webhooks.Manager
+	jobs *queue.Queue
 }
 
-func handleIncomingMsg(bot bot_webhooks.Manager, cfg *config, body func() livechat.Push) http.HandlerFunc {
+func (m *queuedManager) UninstallApp(ctx context.Context, id livechat.LicenseID) error {
+	defer m.jobs.Remove(id)
+	return m.Manager.UninstallApp(ctx, id)
+}
+
+// handleIncomingMsg acknowledges push as soon as it is queued. Push is
+// rejected with 503 if queue is full, so LiveChat delivers it again later.
+// Redelivered pushes are acknowledged without being handled again.
+func handleIncomingMsg(bot bot_webhooks.Manager, jobs *queue.Queue, seen dedup.Store, body func() livechat.Push) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := auth.WithClientID(r.Context(), cfg.Credentials.ClientID)
+		rawBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			sendError(w, err)
+			return
+		}
 
 		bodyMsg := body()
-		if err := json.NewDecoder(r.Body).Decode(&bodyMsg); err != nil {
+		if err := json.Unmarshal(rawBody, &bodyMsg); err != nil {
 			sendError(w, err)
 			return
 		}
 
-		if err := bot.Redirect(ctx, bodyMsg); err != nil {
-			sendError(w, err)
+		logEntry := log.WithFields(log.Fields{
+			"license_id": bodyMsg.GetLicenseID(),
+			"chat_id":    bodyMsg.GetChatID(),
+			"action":     bodyMsg.GetAction(),
+		})
+
+		key := dedup.Key(bodyMsg, rawBody)
+		if !seen.Add(key) {
+			metrics.DuplicatedPushes.WithLabelValues(bodyMsg.GetAction()).Inc()
+			logEntry.WithField("key", key).Info("Ignored redelivered webhook")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		err = jobs.Push(bodyMsg.GetLicenseID(), bodyMsg.GetChatID(), func(ctx context.Context) {
+			if err := bot.Redirect(ctx, bodyMsg); err != nil {
+				logEntry.WithError(err).Error("Cannot handle webhook")
+			}
+		})
+		if err != nil {
+			seen.Remove(key)
+			metrics.RejectedPushes.WithLabelValues(strconv.Itoa(int(bodyMsg.GetLicenseID()))).Inc()
+			logEntry.WithError(err).Warn("Rejected webhook")
+			w.WriteHeader(http.StatusServiceUnavailable)
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 	})
 }
+
+// verifySecretKey rejects every push which was not signed with
+// the secret key registered together with the webhook.
+func verifySecretKey(bot bot_webhooks.Manager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rawBody, err := io.ReadAll(r.Body)
+			if err != nil {
+				sendError(w, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(rawBody))
+
+			var signature struct {
+				LicenseID livechat.LicenseID `json:"license_id"`
+				SecretKey string             `json:"secret_key"`
+			}
+			if err := json.Unmarshal(rawBody, &signature); err != nil {
+				sendError(w, err)
+				return
+			}
+
+			if err := bot.VerifySecretKey(signature.LicenseID, signature.SecretKey); err != nil {
+				log.WithError(err).WithField("license_id", signature.LicenseID).Warn("Rejected webhook with invalid secret key")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livechat/onboarding/bot/bot_webhooks"
+	"github.com/livechat/onboarding/bot/dedup"
+	"github.com/livechat/onboarding/bot/queue"
+	"github.com/livechat/onboarding/livechat"
+	"github.com/stretchr/testify/assert"
+)
+
+type secretKeyManager struct {
+	bot_webhooks.Manager
+	secretKey string
+}
+
+func (m *secretKeyManager) VerifySecretKey(id livechat.LicenseID, secretKey string) error {
+	if id != 1234 || secretKey != m.secretKey {
+		return errors.New("invalid secret key")
+	}
+	return nil
+}
+
+func (m *secretKeyManager) Redirect(context.Context, livechat.Push) error { return nil }
+
+func Test_VerifySecretKey(t *testing.T) {
+	handler := verifySecretKey(&secretKeyManager{secretKey: "valid_key"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := map[string]struct {
+		body   string
+		status int
+	}{
+		"valid key":       {`{"license_id": 1234, "secret_key": "valid_key"}`, http.StatusOK},
+		"invalid key":     {`{"license_id": 1234, "secret_key": "random secret key"}`, http.StatusUnauthorized},
+		"missing key":     {`{"license_id": 1234}`, http.StatusUnauthorized},
+		"invalid license": {`{"license_id": 4321, "secret_key": "valid_key"}`, http.StatusUnauthorized},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks/incoming_chat", strings.NewReader(tt.body)))
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
+
+type redirectManager struct {
+	bot_webhooks.Manager
+	redirected chan livechat.Push
+	release    chan bool
+}
+
+func (m *redirectManager) Redirect(ctx context.Context, push livechat.Push) error {
+	<-m.release
+	m.redirected <- push
+	return nil
+}
+
+func Test_HandleIncomingMsg_Queue(t *testing.T) {
+	manager := &redirectManager{redirected: make(chan livechat.Push, 2), release: make(chan bool)}
+	jobs := queue.New(context.Background(), &queue.Config{Workers: 1, Size: 1})
+	handler := handleIncomingMsg(manager, jobs, dedup.NewLRU(10, time.Minute), func() livechat.Push {
+		return &livechat.PushIncomingChat{}
+	})
+
+	send := func(chatID string) int {
+		return helperSendPush(handler, `{"action": "incoming_chat", "license_id": 1234, "payload": {"chat": {"id": "`+chatID+`"}}}`)
+	}
+
+	// the first push is taken by worker, the second waits in backlog
+	assert.Equal(t, http.StatusOK, send("chat_1"))
+	assert.Eventually(t, func() bool { return send("chat_2") == http.StatusOK }, time.Second, time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, send("chat_3"))
+
+	close(manager.release)
+	assert.NoError(t, jobs.Drain(context.Background()))
+	assert.Len(t, manager.redirected, 2)
+	assert.Equal(t, livechat.ChatID("chat_1"), (<-manager.redirected).GetChatID())
+	assert.Equal(t, http.StatusServiceUnavailable, send("chat_4"))
+}
+
+func Test_HandleIncomingMsg_Dedup(t *testing.T) {
+	manager := &redirectManager{redirected: make(chan livechat.Push, 10), release: make(chan bool)}
+	close(manager.release)
+
+	seen := dedup.NewLRU(10, time.Minute)
+	jobs := queue.New(context.Background(), &queue.Config{Workers: 1, Size: 10})
+	handler := handleIncomingMsg(manager, jobs, seen, func() livechat.Push {
+		return &livechat.PushIncomingMessage{}
+	})
+
+	event := func(eventID, text string) string {
+		return `{"action": "incoming_event", "license_id": 1234, "payload": {"chat_id": "chat_1", "event": {"id": "` + eventID + `", "type": "message", "text": "` + text + `"}}}`
+	}
+
+	assert.Equal(t, http.StatusOK, helperSendPush(handler, event("event_1", "Hello")))
+	assert.Equal(t, http.StatusOK, helperSendPush(handler, event("event_1", "Hello")))
+	assert.Equal(t, http.StatusOK, helperSendPush(handler, event("event_2", "Hello")))
+	assert.NoError(t, jobs.Drain(context.Background()))
+	assert.Len(t, manager.redirected, 2)
+
+	// push rejected by closed queue is not remembered, so it is handled when delivered again
+	assert.Equal(t, http.StatusServiceUnavailable, helperSendPush(handler, event("event_3", "Hello")))
+	assert.Equal(t, 2, seen.Len())
+}
+
+type uninstallManager struct {
+	bot_webhooks.Manager
+}
+
+func (m *uninstallManager) UninstallApp(context.Context, livechat.LicenseID) error {
+	return errors.New("cannot unregister webhooks")
+}
+
+func Test_QueuedManager_UninstallApp(t *testing.T) {
+	jobs := queue.New(context.Background(), &queue.Config{Workers: 1, Size: 1})
+	manager := &queuedManager{Manager: &uninstallManager{}, jobs: jobs}
+
+	started, release := make(chan bool), make(chan bool)
+	assert.NoError(t, jobs.Push(1234, "chat_1", func(context.Context) {
+		started <- true
+		<-release
+	}))
+	<-started
+	assert.NoError(t, jobs.Push(1234, "chat_1", func(context.Context) {}))
+
+	// workers of removed license are started again, so backlog is empty
+	assert.Error(t, manager.UninstallApp(context.Background(), 1234))
+	assert.NoError(t, jobs.Push(1234, "chat_1", func(context.Context) {}))
+
+	close(release)
+	assert.NoError(t, jobs.Drain(context.Background()))
+}
+
+func helperSendPush(handler http.Handler, body string) int {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body)))
+	return w.Code
+}